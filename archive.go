@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// endpoint arquivar card; sai da coluna sem alterar completed_at
+func (app *App) archiveCard(c *fiber.Ctx) error {
+	cardID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var archivedAt sql.NullTime
+	err = tx.QueryRow(ctx, "SELECT archived_at FROM cards WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", cardID).Scan(&archivedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Card não encontrado")
+		}
+		return internalError(c, "Erro ao buscar o card")
+	}
+	if archivedAt.Valid {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "archived_at", Code: "already_archived", Message: "o card já está arquivado"}}})
+	}
+
+	var card Card
+	err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW(), version = version + 1
+		WHERE id = $1 RETURNING `+cardSelectColumns, cardID, userID), &card)
+	if err != nil {
+		return internalError(c, "Erro ao arquivar o card")
+	}
+	if _, err := tx.Exec(ctx, `UPDATE cards SET position = position - 1
+		WHERE column_id = $1 AND position > $2 AND archived_at IS NULL AND deleted_at IS NULL`, card.ColumnID, card.Position); err != nil {
+		return internalError(c, "Erro ao reordenar coluna")
+	}
+	if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventArchived, "archived_at", nil, card.ArchivedAt); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar arquivamento")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "CARDS_ARCHIVED", SenderID: userID, Payload: fiber.Map{"card_ids": []int{cardID}}})
+	setVersionETag(c, card.Version)
+	return c.JSON(card)
+}
+
+// endpoint desarquivar card; volta no fim da coluna
+func (app *App) unarchiveCard(c *fiber.Ctx) error {
+	cardID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var columnID int
+	var archivedAt sql.NullTime
+	err = tx.QueryRow(ctx, "SELECT column_id, archived_at FROM cards WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", cardID).Scan(&columnID, &archivedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Card não encontrado")
+		}
+		return internalError(c, "Erro ao buscar o card")
+	}
+	if !archivedAt.Valid {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "archived_at", Code: "not_archived", Message: "o card não está arquivado"}}})
+	}
+	var maxPos sql.NullInt64
+	if err := tx.QueryRow(ctx, "SELECT MAX(position) FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL", columnID).Scan(&maxPos); err != nil {
+		return internalError(c, "Erro ao calcular a posição do card")
+	}
+
+	var card Card
+	err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET archived_at = NULL, archived_by = NULL, position = $2, updated_at = NOW(), version = version + 1
+		WHERE id = $1 RETURNING `+cardSelectColumns, cardID, int(maxPos.Int64)+1), &card)
+	if err != nil {
+		return internalError(c, "Erro ao desarquivar o card")
+	}
+	if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventUnarchived, "archived_at", archivedAt.Time, nil); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar desarquivamento")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "CARD_CREATED", SenderID: userID, Payload: card})
+	setVersionETag(c, card.Version)
+	return c.JSON(card)
+}
+
+// endpoint arquivar em lote os cards da coluna mais antigos que N dias
+// (pela conclusão, ou pela última alteração quando não concluídos)
+func (app *App) archiveColumnCards(c *fiber.Ctx) error {
+	columnID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		OlderThanDays *int `json:"older_than_days"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	var v Validator
+	if payload.OlderThanDays == nil {
+		v.Add("older_than_days", "required", "campo obrigatório")
+	} else {
+		v.NonNegative("older_than_days", *payload.OlderThanDays)
+	}
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW(), version = version + 1
+		WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL
+		  AND COALESCE(completed_at, updated_at) < NOW() - make_interval(days => $3)
+		RETURNING id, archived_at`, columnID, userID, *payload.OlderThanDays)
+	if err != nil {
+		log.Printf("Erro ao arquivar cards da coluna %d: %v", columnID, err)
+		return internalError(c, "Erro ao arquivar cards")
+	}
+	type archived struct {
+		id int
+		at sql.NullTime
+	}
+	var cards []archived
+	for rows.Next() {
+		var a archived
+		if err := rows.Scan(&a.id, &a.at); err != nil {
+			rows.Close()
+			return internalError(c, "Erro ao ler cards arquivados")
+		}
+		cards = append(cards, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return internalError(c, "Erro ao arquivar cards")
+	}
+
+	cardIDs := make([]int, 0, len(cards))
+	for _, a := range cards {
+		if err := app.recordCardEvent(tx, a.id, boardID, userID, CardEventArchived, "archived_at", nil, a.at.Time); err != nil {
+			log.Printf("Erro ao registrar histórico do card %d: %v", a.id, err)
+			return internalError(c, "Erro ao registrar histórico")
+		}
+		cardIDs = append(cardIDs, a.id)
+	}
+	if len(cardIDs) > 0 {
+		if err := compactCardPositions(ctx, tx, columnID); err != nil {
+			return internalError(c, "Erro ao reordenar coluna")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar arquivamento")
+	}
+
+	if len(cardIDs) > 0 {
+		app.broadcast(boardID, WsMessage{Type: "CARDS_ARCHIVED", SenderID: userID, Payload: fiber.Map{"card_ids": cardIDs}})
+	}
+	return c.JSON(fiber.Map{"archived": len(cardIDs), "card_ids": cardIDs})
+}
+
+// renumerar as posições dos cards visíveis da coluna a partir de 1
+func compactCardPositions(ctx context.Context, tx pgx.Tx, columnID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE cards ca SET position = ordered.rn
+		FROM (
+			SELECT id, ROW_NUMBER() OVER (ORDER BY position, id) AS rn
+			FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL
+		) ordered
+		WHERE ca.id = ordered.id AND ca.position <> ordered.rn`, columnID)
+	return err
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// pool ou transação, para carregar dados relacionados aos cards
+type cardQuerier interface {
+	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
+}
+
+// responsáveis de vários cards numa consulta só, na ordem de atribuição
+func (app *App) loadCardAssignees(ctx context.Context, q cardQuerier, cardIDs []int) (map[int][]UserSummary, error) {
+	byCard := make(map[int][]UserSummary)
+	if len(cardIDs) == 0 {
+		return byCard, nil
+	}
+	rows, err := q.Query(ctx, `
+		SELECT ca.card_id, u.id::text, u.email,
+		       COALESCE(u.raw_user_meta_data->>'username', u.email), COALESCE(u.raw_user_meta_data->>'avatar_url', '')
+		FROM card_assignees ca JOIN auth.users u ON u.id = ca.user_id
+		WHERE ca.card_id = ANY($1)
+		ORDER BY ca.created_at, u.id`, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cardID int
+		var email string
+		var user UserSummary
+		if err := rows.Scan(&cardID, &user.ID, &email, &user.Name, &user.Avatar); err != nil {
+			return nil, err
+		}
+		if name, ok := userDisplayNameMap[email]; ok {
+			user.Name = name
+		}
+		byCard[cardID] = append(byCard[cardID], user)
+	}
+	return byCard, rows.Err()
+}
+
+// preencher etiquetas e responsáveis dos cards
+func (app *App) attachCardDetails(ctx context.Context, cards []*Card) error {
+	if err := app.attachCardLabels(ctx, cards); err != nil {
+		return err
+	}
+	ids := make([]int, len(cards))
+	for i, card := range cards {
+		ids[i] = card.ID
+	}
+	byCard, err := app.loadCardAssignees(ctx, app.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, card := range cards {
+		card.Assignees = byCard[card.ID]
+		if card.Assignees == nil {
+			card.Assignees = []UserSummary{}
+		}
+	}
+	return nil
+}
+
+// substituir os responsáveis do card; ids que não existem em auth.users são ignorados.
+// devolve o conjunto anterior, o novo e os ids recém-adicionados
+func (app *App) replaceCardAssignees(ctx context.Context, tx pgx.Tx, cardID int, userIDs []string) (before, after []UserSummary, added []string, err error) {
+	userIDs = nonNilStrings(userIDs)
+	current, err := app.loadCardAssignees(ctx, tx, []int{cardID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	before = current[cardID]
+	if _, err := tx.Exec(ctx, "DELETE FROM card_assignees WHERE card_id = $1 AND NOT (user_id::text = ANY($2))", cardID, userIDs); err != nil {
+		return nil, nil, nil, err
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO card_assignees (card_id, user_id)
+		SELECT $1, u.id FROM auth.users u WHERE u.id::text = ANY($2)
+		ON CONFLICT DO NOTHING`, cardID, userIDs); err != nil {
+		return nil, nil, nil, err
+	}
+	current, err = app.loadCardAssignees(ctx, tx, []int{cardID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	after = current[cardID]
+
+	previous := make(map[string]bool, len(before))
+	for _, u := range before {
+		previous[u.ID] = true
+	}
+	for _, u := range after {
+		if !previous[u.ID] {
+			added = append(added, u.ID)
+		}
+	}
+	return before, after, added, nil
+}
+
+// assigned_to é só o nome de exibição do primeiro responsável; usado em UPDATE cards
+const assignedToFromAssignees = `COALESCE((
+	SELECT COALESCE(u.raw_user_meta_data->>'username', u.email)
+	FROM card_assignees ca JOIN auth.users u ON u.id = ca.user_id
+	WHERE ca.card_id = cards.id ORDER BY ca.created_at, u.id LIMIT 1), '')`
+
+// responsáveis inválidos: usuário inexistente ou sem acesso ao quadro
+func (app *App) assigneeProblems(ctx context.Context, tx pgx.Tx, boardID int, field string, userIDs []string) ([]FieldError, error) {
+	existing := make(map[string]bool, len(userIDs))
+	rows, err := tx.Query(ctx, "SELECT id::text FROM auth.users WHERE id::text = ANY($1)", nonNilStrings(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	var v Validator
+	for i, userID := range userIDs {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if !existing[userID] {
+			v.Add(name, "not_found", "usuário não encontrado")
+			continue
+		}
+		role, err := boardRoleIn(ctx, tx, userID, boardID)
+		if err != nil {
+			return nil, err
+		}
+		if role == "" {
+			v.Add(name, "no_access", "usuário sem acesso ao quadro")
+		}
+	}
+	return v.fields, nil
+}
+
+// responsáveis pedidos no corpo do card, já validados: assignee_ids quando enviado;
+// senão o assigned_to em texto dos clientes antigos, resolvido para o usuário correspondente
+func (app *App) requestedAssignees(ctx context.Context, tx pgx.Tx, boardID int, assigneeIDs []string, assignedTo string) ([]string, []FieldError, error) {
+	if assigneeIDs != nil {
+		userIDs := uniqueStrings(assigneeIDs)
+		problems, err := app.assigneeProblems(ctx, tx, boardID, "assignee_ids", userIDs)
+		return userIDs, problems, err
+	}
+	if assignedTo == "" {
+		return []string{}, nil, nil
+	}
+	var userID string
+	err := tx.QueryRow(ctx, `SELECT id::text FROM auth.users
+		WHERE raw_user_meta_data->>'username' = $1 OR email = $1
+		ORDER BY (raw_user_meta_data->>'username' = $1) DESC LIMIT 1`, assignedTo).Scan(&userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, []FieldError{{Field: "assigned_to", Code: "not_found", Message: "usuário não encontrado"}}, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	problems, err := app.assigneeProblems(ctx, tx, boardID, "assigned_to", []string{userID})
+	return []string{userID}, problems, err
+}
+
+// trocar os responsáveis na transação do handler e registrar o histórico;
+// devolve a lista nova, os ids recém-adicionados e se houve mudança
+func (app *App) updateCardAssignees(ctx context.Context, tx pgx.Tx, cardID, boardID int, actorID string, userIDs []string) ([]UserSummary, []string, bool, error) {
+	before, after, added, err := app.replaceCardAssignees(ctx, tx, cardID, userIDs)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if after == nil {
+		after = []UserSummary{}
+	}
+	changed := len(added) > 0 || len(before) != len(after)
+	if changed {
+		if err := app.recordCardEvent(tx, cardID, boardID, actorID, CardEventAssigned, "assignees", userNames(before), userNames(after)); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return after, added, changed, nil
+}
+
+// tira os responsáveis sem acesso ao board (card movido para outro board, ou usuário que
+// perdeu o acesso) e atualiza assigned_to, sem mexer na versão; devolve se tirou alguém
+func (app *App) dropAssigneesWithoutAccess(ctx context.Context, tx pgx.Tx, cardID, boardID int, actorID string) (bool, error) {
+	current, err := app.loadCardAssignees(ctx, tx, []int{cardID})
+	if err != nil {
+		return false, err
+	}
+	keep, err := assigneesWithAccess(ctx, tx, boardID, current[cardID])
+	if err != nil {
+		return false, err
+	}
+	if len(keep) == len(current[cardID]) {
+		return false, nil
+	}
+	if _, _, _, err := app.updateCardAssignees(ctx, tx, cardID, boardID, actorID, keep); err != nil {
+		return false, err
+	}
+	_, err = tx.Exec(ctx, "UPDATE cards SET assigned_to = "+assignedToFromAssignees+" WHERE id = $1", cardID)
+	return err == nil, err
+}
+
+// ids dos usuários que têm algum papel no board
+func assigneesWithAccess(ctx context.Context, tx pgx.Tx, boardID int, users []UserSummary) ([]string, error) {
+	keep := make([]string, 0, len(users))
+	for _, u := range users {
+		role, err := boardRoleIn(ctx, tx, u.ID, boardID)
+		if err != nil {
+			return nil, err
+		}
+		if role.AtLeast(RoleViewer) {
+			keep = append(keep, u.ID)
+		}
+	}
+	return keep, nil
+}
+
+// avisar cada responsável recém-adicionado; cada aviso roda num savepoint para que
+// uma falha só seja logada sem abortar a transação do card
+func (app *App) notifyNewAssignees(ctx context.Context, tx pgx.Tx, userIDs []string, boardID, cardID int, title string) {
+	for _, userID := range userIDs {
+		sp, err := tx.Begin(ctx)
+		if err == nil {
+			err = app.createNotification(sp, Notification{
+				UserID:         userID,
+				Type:           "new_task_assigned",
+				Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", title),
+				RelatedBoardID: &boardID,
+				RelatedCardID:  &cardID,
+			})
+			if err == nil {
+				err = sp.Commit(ctx)
+			} else {
+				sp.Rollback(ctx)
+			}
+		}
+		if err != nil {
+			log.Printf("Erro ao notificar responsável %s do card %d: %v", userID, cardID, err)
+		}
+	}
+}
+
+// endpoint definir os responsáveis do card (substitui o conjunto)
+func (app *App) setCardAssignees(c *fiber.Ctx) error {
+	var payload struct {
+		UserIDs []string `json:"user_ids"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	return app.changeCardAssignees(c, func([]string) []string { return uniqueStrings(payload.UserIDs) })
+}
+
+// endpoint adicionar responsável ao card
+func (app *App) addCardAssignee(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	return app.changeCardAssignees(c, func(current []string) []string { return uniqueStrings(append(current, userID)) })
+}
+
+// endpoint remover responsável do card
+func (app *App) removeCardAssignee(c *fiber.Ctx) error {
+	userID := c.Params("userId")
+	return app.changeCardAssignees(c, func(current []string) []string {
+		next := make([]string, 0, len(current))
+		for _, id := range current {
+			if id != userID {
+				next = append(next, id)
+			}
+		}
+		return next
+	})
+}
+
+// aplicar a mudança nos responsáveis, manter assigned_to com o primeiro deles,
+// registrar histórico, notificar os novos e avisar o board
+func (app *App) changeCardAssignees(c *fiber.Ctx, change func(current []string) []string) error {
+	cardID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
+	actorID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	// trava o card para mudanças concorrentes nos responsáveis
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM cards WHERE id = $1 FOR UPDATE", cardID); err != nil {
+		return internalError(c, "Erro ao buscar o card")
+	}
+	// quem perdeu o acesso sai antes; assim a validação abaixo só pode falhar
+	// por causa dos ids que estão entrando agora
+	dropped, err := app.dropAssigneesWithoutAccess(ctx, tx, cardID, boardID, actorID)
+	if err != nil {
+		log.Printf("Erro ao remover responsáveis sem acesso do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao atualizar responsáveis")
+	}
+	current, err := app.loadCardAssignees(ctx, tx, []int{cardID})
+	if err != nil {
+		return internalError(c, "Erro ao buscar responsáveis do card")
+	}
+	currentIDs := make([]string, len(current[cardID]))
+	for i, u := range current[cardID] {
+		currentIDs[i] = u.ID
+	}
+	next := nonNilStrings(change(currentIDs))
+
+	// só usuários existentes e com acesso ao quadro
+	problems, err := app.assigneeProblems(ctx, tx, boardID, "user_ids", next)
+	if err != nil {
+		return internalError(c, "Erro ao validar responsáveis")
+	}
+	if len(problems) > 0 {
+		return validationFailed(c, &ValidationError{Fields: problems})
+	}
+
+	_, added, changed, err := app.updateCardAssignees(ctx, tx, cardID, boardID, actorID, next)
+	if err != nil {
+		log.Printf("Erro ao atualizar responsáveis do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao atualizar responsáveis")
+	}
+
+	var card Card
+	if !changed && !dropped {
+		if err := scanCard(tx.QueryRow(ctx, "SELECT "+cardSelectColumns+" FROM cards WHERE id = $1", cardID), &card); err != nil {
+			return internalError(c, "Erro ao buscar o card")
+		}
+	} else {
+		err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET assigned_to = `+assignedToFromAssignees+`,
+			updated_at = NOW(), version = version + 1
+			WHERE id = $1 RETURNING `+cardSelectColumns, cardID), &card)
+		if err != nil {
+			return internalError(c, "Erro ao atualizar o card")
+		}
+		app.notifyNewAssignees(ctx, tx, added, boardID, cardID, card.Title)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar responsáveis")
+	}
+
+	if err := app.attachCardDetails(ctx, []*Card{&card}); err != nil {
+		log.Printf("Erro ao carregar detalhes do card %d: %v", cardID, err)
+	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_UPDATED", SenderID: actorID, Payload: card})
+	setVersionETag(c, card.Version)
+	return c.JSON(card)
+}
+
+// cards com assigned_to em texto passam a ter o usuário correspondente em card_assignees
+func (app *App) migrateCardAssignees(ctx context.Context) error {
+	tag, err := app.db.Exec(ctx, `
+		INSERT INTO card_assignees (card_id, user_id)
+		SELECT DISTINCT ON (ca.id) ca.id, u.id
+		FROM cards ca
+		JOIN auth.users u ON u.raw_user_meta_data->>'username' = ca.assigned_to OR u.email = ca.assigned_to
+		WHERE COALESCE(ca.assigned_to, '') <> ''
+		ORDER BY ca.id, (u.raw_user_meta_data->>'username' = ca.assigned_to) DESC
+		ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	var unresolved int
+	app.db.QueryRow(ctx, `SELECT COUNT(*) FROM cards ca
+		WHERE COALESCE(ca.assigned_to, '') <> ''
+		  AND NOT EXISTS (SELECT 1 FROM card_assignees cs WHERE cs.card_id = ca.id)`).Scan(&unresolved)
+	log.Printf("Responsáveis migrados: %d cards; %d com assigned_to sem usuário correspondente", tag.RowsAffected(), unresolved)
+	return nil
+}
+
+func userNames(users []UserSummary) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != "" && !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
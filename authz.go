@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// recursos que pertencem a um board
+type resourceKind int
+
+const (
+	resourceBoard resourceKind = iota
+	resourceColumn
+	resourceCard
+)
+
+func (k resourceKind) notFoundMessage() string {
+	switch k {
+	case resourceColumn:
+		return "Coluna não encontrada"
+	case resourceCard:
+		return "Card não encontrado"
+	default:
+		return "Quadro não encontrado"
+	}
+}
+
+// resolver o board dono do recurso
+func (app *App) resourceBoardID(kind resourceKind, id int) (int, error) {
+	switch kind {
+	case resourceColumn:
+		return app.getBoardIDFromColumn(id)
+	case resourceCard:
+		return app.getBoardIDFromCard(id)
+	default:
+		var boardID int
+		err := app.db.QueryRow(context.Background(), "SELECT id FROM boards WHERE id = $1 AND deleted_at IS NULL", id).Scan(&boardID)
+		return boardID, err
+	}
+}
+
+// consultas usadas na autorização; o App responde pelo banco, os testes usam um fake
+type accessLookup interface {
+	resourceBoardID(kind resourceKind, id int) (int, error)
+	boardRole(userID string, boardID int) (BoardRole, error)
+}
+
+func (app *App) lookup() accessLookup {
+	if app.access != nil {
+		return app.access
+	}
+	return app
+}
+
+// recurso -> board -> papel mínimo; escreve 404/403 quando negado
+func (app *App) authorize(c *fiber.Ctx, kind resourceKind, id int, min BoardRole) (int, BoardRole, bool) {
+	userID := c.Locals("userID").(string)
+	boardID, err := app.lookup().resourceBoardID(kind, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			notFound(c, kind.notFoundMessage())
+		} else {
+			internalError(c, "Erro ao verificar permissão")
+		}
+		return 0, "", false
+	}
+	role, err := app.lookup().boardRole(userID, boardID)
+	if err != nil || role == "" {
+		forbidden(c)
+		return 0, "", false
+	}
+	if !role.AtLeast(min) {
+		insufficientRole(c, min)
+		return 0, "", false
+	}
+	return boardID, role, true
+}
+
+// middleware: exige o papel mínimo no board do recurso em c.Params(param);
+// guarda board e papel em Locals("boardID") e Locals("boardRole")
+func (app *App) requireAccess(kind resourceKind, param string, min BoardRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Params(param))
+		if err != nil {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: param, Code: "invalid_id", Message: "ID inválido"}}})
+		}
+		return app.grantAccess(c, kind, id, min)
+	}
+}
+
+// middleware: como requireAccess, para rotas que recebem o id do recurso no corpo JSON
+func (app *App) requireBodyAccess(kind resourceKind, field string, min BoardRole) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(c.Body(), &body); err != nil {
+			return invalidBody(c)
+		}
+		var id int
+		if err := json.Unmarshal(body[field], &id); err != nil || id <= 0 {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: field, Code: "required", Message: "campo obrigatório"}}})
+		}
+		return app.grantAccess(c, kind, id, min)
+	}
+}
+
+func (app *App) grantAccess(c *fiber.Ctx, kind resourceKind, id int, min BoardRole) error {
+	boardID, role, ok := app.authorize(c, kind, id, min)
+	if !ok {
+		return nil
+	}
+	c.Locals("boardID", boardID)
+	c.Locals("boardRole", role)
+	return c.Next()
+}
+
+// exigir papel mínimo num board já conhecido (ex.: board vindo do corpo)
+func (app *App) requireBoardRole(c *fiber.Ctx, boardID int, min BoardRole) bool {
+	_, _, ok := app.authorize(c, resourceBoard, boardID, min)
+	return ok
+}
+
+// board já autorizado pelo requireAccess
+func authorizedBoardID(c *fiber.Ctx) int {
+	boardID, _ := c.Locals("boardID").(int)
+	return boardID
+}
+
+func forbidden(c *fiber.Ctx) error {
+	return accessDenied(c, "Acesso negado a este quadro.")
+}
+
+// 403 com mensagem específica da ação
+func accessDenied(c *fiber.Ctx, message string) error {
+	return errorResponse(c, fiber.StatusForbidden, "forbidden", message, nil)
+}
+
+func insufficientRole(c *fiber.Ctx, min BoardRole) error {
+	return errorResponse(c, fiber.StatusForbidden, "insufficient_role",
+		fmt.Sprintf("Esta ação exige o papel %s ou superior neste quadro.", min), nil)
+}
+
+func notFound(c *fiber.Ctx, message string) error {
+	return errorResponse(c, fiber.StatusNotFound, "not_found", message, nil)
+}
+
+// colunas de status criadas automaticamente nos quadros públicos
+func isSystemColumnTitle(title string) bool {
+	switch strings.ToLower(strings.TrimSpace(title)) {
+	case "solucionado", "não solucionado":
+		return true
+	}
+	return false
+}
+
+// coluna de sistema = título reservado em quadro público
+func (app *App) isSystemColumn(columnID int) (bool, error) {
+	var title string
+	var isPublic bool
+	err := app.db.QueryRow(context.Background(), `
+		SELECT col.title, b.is_public FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.id = $1`, columnID).Scan(&title, &isPublic)
+	if err != nil {
+		return false, err
+	}
+	return isPublic && isSystemColumnTitle(title), nil
+}
+
+func systemColumnProtected(c *fiber.Ctx) error {
+	return errorResponse(c, fiber.StatusForbidden, "system_column",
+		"As colunas Solucionado e Não Solucionado não podem ser renomeadas nem excluídas.", nil)
+}
+
+func reservedColumnTitle() error {
+	return &ValidationError{Fields: []FieldError{{Field: "title", Code: "reserved", Message: "título reservado para as colunas de status"}}}
+}
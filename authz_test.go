@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	testBoardID  = 1
+	testCardID   = 10
+	testColumnID = 20
+	missingID    = 99
+	testSecret   = "test-secret"
+)
+
+// papéis fixos no board de teste, sem banco
+type fakeAccess map[string]BoardRole
+
+func (f fakeAccess) resourceBoardID(kind resourceKind, id int) (int, error) {
+	switch {
+	case kind == resourceCard && id == testCardID,
+		kind == resourceColumn && id == testColumnID,
+		kind == resourceBoard && id == testBoardID:
+		return testBoardID, nil
+	}
+	return 0, pgx.ErrNoRows
+}
+
+func (f fakeAccess) boardRole(userID string, boardID int) (BoardRole, error) {
+	return f[userID], nil
+}
+
+func testToken(t *testing.T, userID string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, SupabaseClaims{
+		UserID: userID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{"authenticated"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// rotas reais com o lookup fake; handlers liberados batem no banco nulo e caem no recover (500)
+func newAuthzTestApp(t *testing.T) *fiber.App {
+	t.Helper()
+	t.Setenv("SUPABASE_JWT_SECRET", testSecret)
+	app := &App{access: fakeAccess{
+		"owner":     RoleOwner,
+		"editor":    RoleEditor,
+		"commenter": RoleCommenter,
+		"viewer":    RoleViewer,
+	}}
+	fiberApp := fiber.New()
+	fiberApp.Use(recover.New())
+	app.setupRoutes(fiberApp)
+	return fiberApp
+}
+
+func errorCode(t *testing.T, body []byte) string {
+	t.Helper()
+	var resp struct {
+		Error APIError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		t.Fatalf("corpo de erro inválido %q: %v", body, err)
+	}
+	return resp.Error.Code
+}
+
+func TestRequireAccessCardAndColumnRoutes(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+		kind   resourceKind
+		min    BoardRole
+	}{
+		{"PUT", "/columns/%d", resourceColumn, RoleEditor},
+		{"DELETE", "/columns/%d", resourceColumn, RoleEditor},
+		{"GET", "/columns/%d/cards", resourceColumn, RoleViewer},
+		{"POST", "/columns/%d/cards", resourceColumn, RoleEditor},
+		{"POST", "/columns/%d/archive", resourceColumn, RoleEditor},
+		{"PUT", "/cards/%d", resourceCard, RoleEditor},
+		{"PATCH", "/cards/%d", resourceCard, RoleEditor},
+		{"DELETE", "/cards/%d", resourceCard, RoleEditor},
+		{"POST", "/cards/%d/archive", resourceCard, RoleEditor},
+		{"POST", "/cards/%d/unarchive", resourceCard, RoleEditor},
+		{"PUT", "/cards/%d/labels", resourceCard, RoleEditor},
+		{"POST", "/cards/%d/labels/1", resourceCard, RoleEditor},
+		{"DELETE", "/cards/%d/labels/1", resourceCard, RoleEditor},
+		{"PUT", "/cards/%d/assignees", resourceCard, RoleEditor},
+		{"POST", "/cards/%d/assignees/u1", resourceCard, RoleEditor},
+		{"DELETE", "/cards/%d/assignees/u1", resourceCard, RoleEditor},
+		{"GET", "/cards/%d/comments", resourceCard, RoleViewer},
+		{"POST", "/cards/%d/comments", resourceCard, RoleCommenter},
+		{"PUT", "/cards/%d/comments/1", resourceCard, RoleCommenter},
+		{"DELETE", "/cards/%d/comments/1", resourceCard, RoleCommenter},
+	}
+	users := []struct {
+		id   string
+		role BoardRole
+	}{
+		{"stranger", ""},
+		{"viewer", RoleViewer},
+		{"commenter", RoleCommenter},
+		{"editor", RoleEditor},
+		{"owner", RoleOwner},
+	}
+	fiberApp := newAuthzTestApp(t)
+
+	for _, rt := range routes {
+		id := testCardID
+		if rt.kind == resourceColumn {
+			id = testColumnID
+		}
+		for _, u := range users {
+			name := fmt.Sprintf("%s %s as %s", rt.method, rt.path, u.id)
+			t.Run(name, func(t *testing.T) {
+				req := httptest.NewRequest(rt.method, "/api"+fmt.Sprintf(rt.path, id), strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+testToken(t, u.id))
+				resp, err := fiberApp.Test(req, -1)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+
+				switch {
+				case u.role == "":
+					if resp.StatusCode != fiber.StatusForbidden || errorCode(t, body) != "forbidden" {
+						t.Fatalf("esperado 403 forbidden, veio %d %s", resp.StatusCode, body)
+					}
+				case !u.role.AtLeast(rt.min):
+					if resp.StatusCode != fiber.StatusForbidden || errorCode(t, body) != "insufficient_role" {
+						t.Fatalf("esperado 403 insufficient_role, veio %d %s", resp.StatusCode, body)
+					}
+				default:
+					if resp.StatusCode == fiber.StatusForbidden || resp.StatusCode == fiber.StatusNotFound {
+						t.Fatalf("papel %s deveria passar pelo requireAccess, veio %d %s", u.role, resp.StatusCode, body)
+					}
+				}
+			})
+		}
+
+		t.Run(fmt.Sprintf("%s %s missing", rt.method, rt.path), func(t *testing.T) {
+			req := httptest.NewRequest(rt.method, "/api"+fmt.Sprintf(rt.path, missingID), strings.NewReader("{}"))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken(t, "owner"))
+			resp, err := fiberApp.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusNotFound {
+				t.Fatalf("esperado 404, veio %d", resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestValidateMoveTarget(t *testing.T) {
+	cases := []struct {
+		name       string
+		oldBoard   int
+		newBoard   int
+		allowCross bool
+		wantErr    bool
+	}{
+		{"mesmo quadro", 1, 1, false, false},
+		{"outro quadro sem pedido", 1, 2, false, true},
+		{"outro quadro com allow_cross_board", 1, 2, true, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateMoveTarget(tc.oldBoard, tc.newBoard, tc.allowCross)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tc.wantErr)
+			}
+			if verr, ok := err.(*ValidationError); ok && verr.Fields[0].Code != "cross_board" {
+				t.Fatalf("código = %s, esperado cross_board", verr.Fields[0].Code)
+			}
+		})
+	}
+}
+
+func TestValidateColumnUnchanged(t *testing.T) {
+	cases := []struct {
+		name      string
+		current   int
+		requested int
+		wantErr   bool
+	}{
+		{"sem column_id no corpo", 5, 0, false},
+		{"mesma coluna", 5, 5, false},
+		{"outra coluna", 5, 6, true},
+		{"coluna de outro quadro", 5, 42, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateColumnUnchanged(tc.current, tc.requested)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("erro = %v, esperado erro: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestEffectiveBoardRole(t *testing.T) {
+	viewer, editor := string(RoleViewer), string(RoleEditor)
+	cases := []struct {
+		name       string
+		userID     string
+		isPublic   bool
+		memberRole *string
+		want       BoardRole
+	}{
+		{"dono", "owner", false, nil, RoleOwner},
+		{"dono de quadro público", "owner", true, &viewer, RoleOwner},
+		{"membro viewer em quadro privado", "u", false, &viewer, RoleViewer},
+		{"membro viewer em quadro público", "u", true, &viewer, RoleEditor},
+		{"membro editor em quadro público", "u", true, &editor, RoleEditor},
+		{"não membro em quadro público", "u", true, nil, RoleEditor},
+		{"não membro em quadro privado", "u", false, nil, ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := effectiveBoardRole(tc.userID, "owner", tc.isPublic, tc.memberRole); got != tc.want {
+				t.Fatalf("papel = %q, esperado %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRequireBodyAccessMoveRoute(t *testing.T) {
+	fiberApp := newAuthzTestApp(t)
+	cases := []struct {
+		name   string
+		user   string
+		body   string
+		status int
+		code   string
+	}{
+		{"sem card_id", "editor", `{"new_column_id": 20}`, fiber.StatusBadRequest, ErrCodeValidation},
+		{"card inexistente", "owner", fmt.Sprintf(`{"card_id": %d, "new_column_id": 20}`, missingID), fiber.StatusNotFound, ""},
+		{"estranho", "stranger", fmt.Sprintf(`{"card_id": %d, "new_column_id": 20}`, testCardID), fiber.StatusForbidden, "forbidden"},
+		{"viewer", "viewer", fmt.Sprintf(`{"card_id": %d, "new_column_id": 20}`, testCardID), fiber.StatusForbidden, "insufficient_role"},
+		{"commenter", "commenter", fmt.Sprintf(`{"card_id": %d, "new_column_id": 20}`, testCardID), fiber.StatusForbidden, "insufficient_role"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/cards/move", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testToken(t, tc.user))
+			resp, err := fiberApp.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tc.status {
+				t.Fatalf("esperado %d, veio %d %s", tc.status, resp.StatusCode, body)
+			}
+			if tc.code != "" && errorCode(t, body) != tc.code {
+				t.Fatalf("esperado %s, veio %s", tc.code, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+const boardSelectColumns = `id, title, COALESCE(description, '') as description, owner_id, created_at, updated_at,
+	COALESCE(color, '') as color, is_public, team, is_default, version`
+
+func scanBoard(row pgx.Row, b *Board) error {
+	return row.Scan(&b.ID, &b.Title, &b.Description, &b.OwnerID, &b.CreatedAt, &b.UpdatedAt, &b.Color, &b.IsPublic,
+		&b.Team, &b.IsDefault, &b.Version)
+}
+
+var boardPatchFields = map[string]patchField{
+	"title":       {Decode: patchString},
+	"description": {Nullable: true, Decode: patchString},
+	"color":       {Nullable: true, Decode: patchString},
+	"is_public":   {Decode: patchBool},
+	"team":        {Nullable: true, Decode: patchString},
+}
+
+// ordenações aceitas na listagem de boards públicos
+var publicBoardSorts = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "lower(title)",
+}
+
+// colunas de status exigidas nos quadros públicos
+var statusColumns = []struct {
+	Title string
+	Color string
+}{
+	{"Solucionado", "#3fb950"},
+	{"Não Solucionado", "#f85149"},
+}
+
+// criar as colunas de status que faltarem no fim do board; retorna se criou alguma
+func ensureStatusColumns(ctx context.Context, tx pgx.Tx, boardID int) (bool, error) {
+	rows, err := tx.Query(ctx, "SELECT title, position FROM columns WHERE board_id = $1 AND deleted_at IS NULL", boardID)
+	if err != nil {
+		return false, err
+	}
+	found := make(map[string]bool)
+	maxPosition := -1
+	for rows.Next() {
+		var title string
+		var position int
+		if err := rows.Scan(&title, &position); err != nil {
+			rows.Close()
+			return false, err
+		}
+		found[strings.ToLower(title)] = true
+		if position > maxPosition {
+			maxPosition = position
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+
+	created := false
+	for _, col := range statusColumns {
+		if found[strings.ToLower(col.Title)] {
+			continue
+		}
+		maxPosition++
+		_, err := tx.Exec(ctx, `INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`,
+			boardID, col.Title, maxPosition, col.Color)
+		if err != nil {
+			return created, err
+		}
+		created = true
+	}
+	return created, nil
+}
+
+// endpoint editar board (substituição completa)
+func (app *App) updateBoard(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	var board Board
+	if err := c.BodyParser(&board); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateBoard(&board); err != nil {
+		return validationFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, board.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
+	}
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE boards SET title = $1, description = $2, color = $3, is_public = $4, team = $5, updated_at = NOW(), version = version + 1
+			  WHERE id = $6 AND ($7 = 0 OR version = $7)
+			  RETURNING ` + boardSelectColumns
+	err = scanBoard(tx.QueryRow(ctx, query,
+		board.Title, board.Description, board.Color, board.IsPublic, board.Team, boardID, expectedVersion), &board)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conditionalUpdateMiss(c, func() (interface{}, int, error) {
+				var current Board
+				err := scanBoard(app.db.QueryRow(context.Background(), "SELECT "+boardSelectColumns+" FROM boards WHERE id = $1", boardID), &current)
+				return current, current.Version, err
+			}, "Quadro não encontrado")
+		}
+		log.Printf("Erro ao atualizar board %d: %v", boardID, err)
+		return internalError(c, "Erro ao atualizar o quadro")
+	}
+	columnsCreated, err := applyBoardVisibility(ctx, tx, &board)
+	if err != nil {
+		log.Printf("Erro ao aplicar visibilidade do board %d: %v", boardID, err)
+		return internalError(c, "Erro ao atualizar o quadro")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar atualização")
+	}
+
+	app.boardUpdated(c, &board, columnsCreated)
+	setVersionETag(c, board.Version)
+	return c.JSON(board)
+}
+
+// endpoint editar board parcialmente
+func (app *App) patchBoard(c *fiber.Ctx) error {
+	columnsCreated := false
+	return patchRecord(app, c, patchTarget[Board]{
+		Table:         "boards",
+		SelectColumns: boardSelectColumns,
+		Fields:        boardPatchFields,
+		Scan:          scanBoard,
+		Validate:      validateBoard,
+		Version:       func(b *Board) int { return b.Version },
+		NotFound:      "Quadro não encontrado",
+		InTx: func(ctx context.Context, tx pgx.Tx, board *Board) (err error) {
+			columnsCreated, err = applyBoardVisibility(ctx, tx, board)
+			return err
+		},
+		OnUpdated: func(c *fiber.Ctx, board *Board) { app.boardUpdated(c, board, columnsCreated) },
+	})
+}
+
+// na transação do UPDATE: board público passa a ter as colunas de status; ao voltar a
+// privado elas viram colunas comuns e o board deixa de ser o padrão do time
+func applyBoardVisibility(ctx context.Context, tx pgx.Tx, board *Board) (bool, error) {
+	if !board.IsPublic && board.IsDefault {
+		if _, err := tx.Exec(ctx, "UPDATE boards SET is_default = false WHERE id = $1", board.ID); err != nil {
+			return false, err
+		}
+		board.IsDefault = false
+	}
+	if !board.IsPublic {
+		return false, nil
+	}
+	return ensureStatusColumns(ctx, tx, board.ID)
+}
+
+// avisar o board; colunas novas fazem os clientes recarregarem o estado e, no board
+// privado, quem não é dono nem membro perde a conexão
+func (app *App) boardUpdated(c *fiber.Ctx, board *Board, columnsCreated bool) {
+	userID := c.Locals("userID").(string)
+	app.broadcast(board.ID, WsMessage{Type: "BOARD_UPDATED", SenderID: userID, Payload: board})
+	if columnsCreated {
+		app.broadcast(board.ID, WsMessage{Type: "BOARD_STATE_UPDATED", SenderID: userID, Payload: nil})
+	}
+	if !board.IsPublic {
+		members, err := app.boardMemberIDs(context.Background(), board.ID)
+		if err != nil {
+			log.Printf("Erro ao buscar membros do board %d: %v", board.ID, err)
+			return
+		}
+		app.disconnectBoardClientsExcept(board.ID, members)
+	}
+}
+
+// dono e membros do board
+func (app *App) boardMemberIDs(ctx context.Context, boardID int) ([]string, error) {
+	rows, err := app.db.Query(ctx, `
+		SELECT owner_id::text FROM boards WHERE id = $1
+		UNION
+		SELECT user_id::text FROM board_memberships WHERE board_id = $1`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// endpoint board por id
+func (app *App) getBoard(c *fiber.Ctx) error {
+	var board Board
+	err := scanBoard(app.db.QueryRow(context.Background(),
+		"SELECT "+boardSelectColumns+" FROM boards WHERE id = $1", authorizedBoardID(c)), &board)
+	if err != nil {
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	setVersionETag(c, board.Version)
+	return c.JSON(board)
+}
+
+// endpoint board público padrão (do time em ?team=, ou o mais recente)
+func (app *App) getDefaultPublicBoard(c *fiber.Ctx) error {
+	var board Board
+	err := scanBoard(app.db.QueryRow(context.Background(), `
+		SELECT `+boardSelectColumns+` FROM boards
+		WHERE is_public = true AND deleted_at IS NULL AND ($1 = '' OR team = $1)
+		ORDER BY is_default DESC, created_at DESC
+		LIMIT 1`, c.Query("team")), &board)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Nenhum quadro público encontrado")
+		}
+		return internalError(c, "Erro ao buscar o quadro padrão")
+	}
+	return c.JSON(board)
+}
+
+// endpoint marcar board como padrão do seu time
+func (app *App) setDefaultBoard(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var board Board
+	if err := scanBoard(tx.QueryRow(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE id = $1 FOR UPDATE", boardID), &board); err != nil {
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	if !board.IsPublic {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "is_public", Code: "not_public", Message: "apenas quadros públicos podem ser o padrão"}}})
+	}
+	// o padrão anterior muda de versão para não servir o snapshot antigo com 304
+	rows, err := tx.Query(ctx, `UPDATE boards SET is_default = false, updated_at = NOW(), version = version + 1
+		WHERE is_default AND id <> $1 AND team IS NOT DISTINCT FROM $2
+		RETURNING `+boardSelectColumns, boardID, board.Team)
+	if err != nil {
+		return internalError(c, "Erro ao atualizar o quadro padrão")
+	}
+	var previous []Board
+	for rows.Next() {
+		var b Board
+		if err := scanBoard(rows, &b); err != nil {
+			rows.Close()
+			return internalError(c, "Erro ao atualizar o quadro padrão")
+		}
+		previous = append(previous, b)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return internalError(c, "Erro ao atualizar o quadro padrão")
+	}
+	err = scanBoard(tx.QueryRow(ctx, `UPDATE boards SET is_default = true, updated_at = NOW(), version = version + 1
+		WHERE id = $1 RETURNING `+boardSelectColumns, boardID), &board)
+	if err != nil {
+		return internalError(c, "Erro ao atualizar o quadro padrão")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar alteração")
+	}
+
+	userID := c.Locals("userID").(string)
+	for i := range previous {
+		app.broadcast(previous[i].ID, WsMessage{Type: "BOARD_UPDATED", SenderID: userID, Payload: previous[i]})
+	}
+	app.broadcast(boardID, WsMessage{Type: "BOARD_UPDATED", SenderID: userID, Payload: board})
+	setVersionETag(c, board.Version)
+	return c.JSON(board)
+}
+
+// o board público mais recente vira o padrão, como era o comportamento do LIMIT 1
+func (app *App) migrateDefaultPublicBoard(ctx context.Context) error {
+	_, err := app.db.Exec(ctx, `
+		UPDATE boards SET is_default = true
+		WHERE id = (SELECT id FROM boards WHERE is_public = true ORDER BY created_at DESC LIMIT 1)
+		  AND NOT EXISTS (SELECT 1 FROM boards WHERE is_default)`)
+	return err
+}
+
+// quadros públicos anteriores às colunas de status; antes o GET do board é que as criava
+func (app *App) migratePublicBoardStatusColumns(ctx context.Context) error {
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	var boardIDs []int
+	rows, err := tx.Query(ctx, "SELECT id FROM boards WHERE is_public = true AND deleted_at IS NULL")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		boardIDs = append(boardIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range boardIDs {
+		if _, err := ensureStatusColumns(ctx, tx, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// coluna com seus cards, como o frontend consome
+type BoardColumn struct {
+	Column
+	Cards []Card `json:"cards"`
+}
+
+// board completo: metadados, etiquetas, colunas ordenadas e cards
+type BoardSnapshot struct {
+	Board   Board         `json:"board"`
+	Labels  []Label       `json:"labels"`
+	Columns []BoardColumn `json:"columns"`
+}
+
+// responsável como vem do json_agg; o email só serve para o nome de exibição
+type snapshotAssignee struct {
+	UserSummary
+	Email string `json:"email"`
+}
+
+// colunas e cards do board, com etiquetas e responsáveis, numa única consulta
+func (app *App) loadBoardColumns(ctx context.Context, boardID int, includeArchived bool) ([]BoardColumn, error) {
+	rows, err := app.db.Query(ctx, `
+		SELECT col.id, col.board_id, col.title, col.position, COALESCE(col.color, '#e4e6ea'), col.version,
+		       ca.id, ca.title, COALESCE(ca.description, ''), COALESCE(ca.assigned_to, ''), COALESCE(ca.priority, 'media'),
+		       ca.due_date, ca.position, ca.created_at, ca.updated_at, ca.completed_at, ca.archived_at, ca.version,
+		       (SELECT COALESCE(json_agg(l ORDER BY lower(l.name)), '[]')
+		          FROM card_labels cl JOIN labels l ON l.id = cl.label_id
+		         WHERE cl.card_id = ca.id),
+		       (SELECT COALESCE(json_agg(json_build_object(
+		                   'id', u.id::text, 'email', u.email,
+		                   'name', COALESCE(u.raw_user_meta_data->>'username', u.email),
+		                   'avatar', COALESCE(u.raw_user_meta_data->>'avatar_url', ''))
+		               ORDER BY a.created_at, u.id), '[]')
+		          FROM card_assignees a JOIN auth.users u ON u.id = a.user_id
+		         WHERE a.card_id = ca.id)
+		FROM columns col
+		LEFT JOIN cards ca ON ca.column_id = col.id AND ca.deleted_at IS NULL AND ($2 OR ca.archived_at IS NULL)
+		WHERE col.board_id = $1 AND col.deleted_at IS NULL
+		ORDER BY col.position, col.id, ca.archived_at IS NOT NULL, ca.position`, boardID, includeArchived)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make([]BoardColumn, 0)
+	for rows.Next() {
+		var col Column
+		var cardID, cardPosition, cardVersion *int
+		var title, description, assignedTo, priority *string
+		var createdAt, updatedAt *time.Time
+		var assignees []snapshotAssignee
+		var card Card
+		if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color, &col.Version,
+			&cardID, &title, &description, &assignedTo, &priority,
+			&card.DueDate, &cardPosition, &createdAt, &updatedAt, &card.CompletedAt, &card.ArchivedAt, &cardVersion,
+			&card.Labels, &assignees); err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 || columns[len(columns)-1].ID != col.ID {
+			columns = append(columns, BoardColumn{Column: col, Cards: make([]Card, 0)})
+		}
+		if cardID == nil {
+			continue
+		}
+		card.ID, card.ColumnID, card.Position, card.Version = *cardID, col.ID, *cardPosition, *cardVersion
+		card.Title, card.Description, card.AssignedTo, card.Priority = *title, *description, *assignedTo, *priority
+		card.CreatedAt, card.UpdatedAt = *createdAt, *updatedAt
+		card.Assignees = make([]UserSummary, len(assignees))
+		for i, a := range assignees {
+			if name, ok := userDisplayNameMap[a.Email]; ok {
+				a.Name = name
+			}
+			card.Assignees[i] = a.UserSummary
+		}
+		last := &columns[len(columns)-1]
+		last.Cards = append(last.Cards, card)
+	}
+	return columns, rows.Err()
+}
+
+// impressão digital barata do board (versão do board e versão/posição de colunas, cards,
+// etiquetas e responsáveis, com os dados de exibição deles), junto com o board e suas etiquetas
+const boardSnapshotHeadQuery = `
+	WITH cols AS (
+		SELECT id, title, position, color, version FROM columns
+		WHERE board_id = $1 AND deleted_at IS NULL
+	), crds AS (
+		SELECT ca.id, ca.column_id, ca.position, ca.version, ca.updated_at, ca.archived_at FROM cards ca
+		JOIN cols ON cols.id = ca.column_id
+		WHERE ca.deleted_at IS NULL AND ($2 OR ca.archived_at IS NULL)
+	)
+	SELECT ` + boardSelectColumns + `,
+	       (SELECT COALESCE(json_agg(l ORDER BY lower(l.name)), '[]') FROM labels l WHERE l.board_id = $1),
+	       (SELECT COALESCE(md5(string_agg(cols::text, ',' ORDER BY id)), '') FROM cols),
+	       (SELECT COUNT(*) || ':' || COALESCE(MAX(updated_at)::text, '') || ':' ||
+	               COALESCE(md5(string_agg(id || ':' || column_id || ':' || position || ':' || version || ':' || (archived_at IS NOT NULL), ',' ORDER BY id)), '')
+	          FROM crds),
+	       (SELECT COALESCE(md5(string_agg(l::text, ',' ORDER BY l.id)), '') FROM labels l WHERE l.board_id = $1),
+	       (SELECT COALESCE(md5(string_agg(cl.card_id || ':' || cl.label_id, ',' ORDER BY cl.card_id, cl.label_id)), '')
+	          FROM card_labels cl JOIN crds ON crds.id = cl.card_id),
+	       (SELECT COALESCE(md5(string_agg(a.card_id || ':' || a.user_id || ':' || COALESCE(u.email, '') || ':' ||
+	                   COALESCE(u.raw_user_meta_data->>'username', '') || ':' || COALESCE(u.raw_user_meta_data->>'avatar_url', ''),
+	                   ',' ORDER BY a.card_id, a.user_id)), '')
+	          FROM card_assignees a JOIN crds ON crds.id = a.card_id JOIN auth.users u ON u.id = a.user_id)
+	FROM boards WHERE id = $1`
+
+// board, etiquetas e o ETag do snapshot, calculado antes de carregar os cards
+// para o 304 não pagar a carga toda
+func (app *App) loadBoardSnapshotHead(ctx context.Context, boardID int, includeArchived bool, snapshot *BoardSnapshot) (string, error) {
+	b := &snapshot.Board
+	var columns, cards, labels, cardLabels, assignees string
+	err := app.db.QueryRow(ctx, boardSnapshotHeadQuery, boardID, includeArchived).Scan(
+		&b.ID, &b.Title, &b.Description, &b.OwnerID, &b.CreatedAt, &b.UpdatedAt, &b.Color, &b.IsPublic,
+		&b.Team, &b.IsDefault, &b.Version,
+		&snapshot.Labels, &columns, &cards, &labels, &cardLabels, &assignees)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%t|%s|%s|%s|%s|%s", b.Version, includeArchived, columns, cards, labels, cardLabels, assignees)))
+	return fmt.Sprintf(`"%x"`, sum[:16]), nil
+}
+
+// endpoint board completo em duas consultas; ETag da impressão digital e 304 com If-None-Match
+func (app *App) getBoardFull(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	includeArchived := c.QueryBool("include_archived")
+	ctx := context.Background()
+
+	var snapshot BoardSnapshot
+	etag, err := app.loadBoardSnapshotHead(ctx, boardID, includeArchived, &snapshot)
+	if err != nil {
+		log.Printf("Erro ao buscar o board %d: %v", boardID, err)
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	c.Set(fiber.HeaderETag, etag)
+	if etagMatches(c.Get(fiber.HeaderIfNoneMatch), etag) {
+		return c.SendStatus(fiber.StatusNotModified)
+	}
+
+	if snapshot.Columns, err = app.loadBoardColumns(ctx, boardID, includeArchived); err != nil {
+		log.Printf("Erro ao carregar colunas do board %d: %v", boardID, err)
+		return internalError(c, "erro ao buscar colunas")
+	}
+
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		return internalError(c, "Erro ao montar o quadro")
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	return c.Send(body)
+}
+
+// If-None-Match aceita lista de ETags, fracas ou fortes, e "*"
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
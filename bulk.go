@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// operações aceitas em /cards/bulk
+const (
+	BulkMove     = "move"
+	BulkAssign   = "assign"
+	BulkPriority = "priority"
+	BulkDueDate  = "due_date"
+	BulkArchive  = "archive"
+	BulkDelete   = "delete"
+)
+
+var bulkOperations = []string{BulkMove, BulkAssign, BulkPriority, BulkDueDate, BulkArchive, BulkDelete}
+
+const maxBulkCards = 200
+
+// coluna de um card carregado para a operação em lote
+type bulkColumn struct {
+	BoardID int
+	Title   string
+}
+
+// endpoint operações em lote; tudo numa transação e um evento por board
+func (app *App) bulkCards(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		CardIDs   []int  `json:"card_ids"`
+		Operation string `json:"operation"`
+		// move
+		ColumnID        int  `json:"column_id"`
+		AllowCrossBoard bool `json:"allow_cross_board"`
+		// assign (assignee_ids ou, nos clientes antigos, assigned_to), priority, due_date (null limpa a data)
+		AssigneeIDs []string   `json:"assignee_ids"`
+		AssignedTo  string     `json:"assigned_to"`
+		Priority    string     `json:"priority"`
+		DueDate     *time.Time `json:"due_date"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+
+	cardIDs := uniqueInts(payload.CardIDs)
+	var v Validator
+	if len(cardIDs) == 0 {
+		v.Add("card_ids", "required", "informe ao menos um card")
+	} else if len(cardIDs) > maxBulkCards {
+		v.Add("card_ids", "too_many", fmt.Sprintf("máximo de %d cards por operação", maxBulkCards))
+	}
+	v.OneOf("operation", payload.Operation, bulkOperations)
+	switch payload.Operation {
+	case BulkMove:
+		if payload.ColumnID <= 0 {
+			v.Add("column_id", "required", "campo obrigatório")
+		}
+	case BulkAssign:
+		v.MaxLength("assigned_to", payload.AssignedTo, maxShortTextLength)
+	case BulkPriority:
+		v.OneOf("priority", payload.Priority, cardPriorities)
+	}
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	cards, columns, err := loadBulkCards(ctx, tx, cardIDs)
+	if err != nil {
+		log.Printf("Erro ao carregar cards para operação em lote: %v", err)
+		return internalError(c, "Erro ao buscar cards")
+	}
+	// cards de boards que o usuário não enxerga respondem igual aos inexistentes
+	roles := make(map[int]BoardRole)
+	for id, card := range cards {
+		boardID := columns[card.ColumnID].BoardID
+		role, ok := roles[boardID]
+		if !ok {
+			if role, err = app.lookup().boardRole(userID, boardID); err != nil {
+				return internalError(c, "Erro ao verificar permissões")
+			}
+			roles[boardID] = role
+		}
+		if role == "" {
+			delete(cards, id)
+		}
+	}
+	if missing := missingCardIDs(cardIDs, cards); len(missing) > 0 {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{
+			Field: "card_ids", Code: "not_found", Message: "cards não encontrados: " + joinInts(missing),
+		}}})
+	}
+
+	// permissão de edição em cada board envolvido
+	boardIDs := make([]int, 0)
+	seenBoards := make(map[int]bool)
+	for _, card := range cards {
+		boardID := columns[card.ColumnID].BoardID
+		if !seenBoards[boardID] {
+			seenBoards[boardID] = true
+			boardIDs = append(boardIDs, boardID)
+		}
+	}
+	sort.Ints(boardIDs)
+	for _, boardID := range boardIDs {
+		if !app.requireBoardRole(c, boardID, RoleEditor) {
+			return nil
+		}
+	}
+
+	// responsáveis resolvidos e validados em cada board envolvido
+	assigneeIDs := make(map[int][]string)
+	if payload.Operation == BulkAssign {
+		for _, boardID := range boardIDs {
+			ids, problems, err := app.requestedAssignees(ctx, tx, boardID, payload.AssigneeIDs, payload.AssignedTo)
+			if err != nil {
+				return internalError(c, "Erro ao validar responsáveis")
+			}
+			if len(problems) > 0 {
+				return validationFailed(c, &ValidationError{Fields: problems})
+			}
+			assigneeIDs[boardID] = ids
+		}
+	}
+
+	var target bulkColumn
+	var nextPosition int
+	if payload.Operation == BulkMove {
+		err := tx.QueryRow(ctx, `SELECT col.board_id, col.title FROM columns col JOIN boards b ON b.id = col.board_id
+			WHERE col.id = $1 AND col.deleted_at IS NULL AND b.deleted_at IS NULL`, payload.ColumnID).Scan(&target.BoardID, &target.Title)
+		if err != nil {
+			return notFound(c, "Coluna de destino não encontrada")
+		}
+		for _, boardID := range boardIDs {
+			if boardID != target.BoardID && !payload.AllowCrossBoard {
+				return validationFailed(c, &ValidationError{Fields: []FieldError{{
+					Field: "column_id", Code: "cross_board", Message: "a coluna de destino pertence a outro quadro",
+				}}})
+			}
+		}
+		if !seenBoards[target.BoardID] && !app.requireBoardRole(c, target.BoardID, RoleEditor) {
+			return nil
+		}
+		var maxPos sql.NullInt64
+		if err := tx.QueryRow(ctx, "SELECT MAX(position) FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL",
+			payload.ColumnID).Scan(&maxPos); err != nil {
+			return internalError(c, "Erro ao calcular a posição dos cards")
+		}
+		nextPosition = int(maxPos.Int64) + 1
+	}
+
+	updated := make(map[int][]Card)
+	removed := make(map[int][]int)
+	compact := make(map[int]bool)
+	allUpdated := make([]Card, 0)
+	allRemoved := make([]int, 0)
+
+	for _, id := range cardIDs {
+		before := cards[id]
+		col := columns[before.ColumnID]
+		boardID := col.BoardID
+		var after Card
+
+		switch payload.Operation {
+		case BulkMove:
+			if before.ColumnID == payload.ColumnID {
+				continue
+			}
+			// etiquetas e responsáveis do board antigo não valem no novo
+			if target.BoardID != boardID {
+				err = app.dropForeignCardLabels(ctx, tx, id, target.BoardID, userID)
+				if err == nil {
+					_, err = app.dropAssigneesWithoutAccess(ctx, tx, id, target.BoardID, userID)
+				}
+				if err != nil {
+					break
+				}
+			}
+			completedAt := before.CompletedAt
+			entering, leaving := isSystemColumnTitle(target.Title), isSystemColumnTitle(col.Title)
+			if entering && !leaving {
+				now := time.Now()
+				completedAt = &now
+			} else if leaving && !entering {
+				completedAt = nil
+			}
+			err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET column_id = $2, position = $3, completed_at = $4,
+				updated_at = NOW(), version = version + 1 WHERE id = $1 RETURNING `+cardSelectColumns,
+				id, payload.ColumnID, nextPosition, completedAt), &after)
+			nextPosition++
+			if err == nil {
+				compact[before.ColumnID] = true
+				err = app.recordCardChanges(tx, target.BoardID, userID, before, after)
+			}
+			if err == nil && target.BoardID != boardID {
+				removed[boardID] = append(removed[boardID], id)
+			}
+			boardID = target.BoardID
+
+		case BulkAssign:
+			if payload.AssigneeIDs == nil && before.AssignedTo == payload.AssignedTo {
+				continue
+			}
+			assignees, added, changed, assignErr := app.updateCardAssignees(ctx, tx, id, boardID, userID, assigneeIDs[boardID])
+			if assignErr == nil && !changed {
+				continue
+			}
+			err = assignErr
+			if err == nil {
+				err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET assigned_to = `+assignedToFromAssignees+`,
+					updated_at = NOW(), version = version + 1 WHERE id = $1 RETURNING `+cardSelectColumns, id), &after)
+			}
+			if err == nil {
+				after.Assignees = assignees
+				app.notifyNewAssignees(ctx, tx, added, boardID, id, after.Title)
+			}
+
+		case BulkPriority:
+			if before.Priority == payload.Priority {
+				continue
+			}
+			err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET priority = $2, updated_at = NOW(), version = version + 1
+				WHERE id = $1 RETURNING `+cardSelectColumns, id, payload.Priority), &after)
+			if err == nil {
+				err = app.recordCardChanges(tx, boardID, userID, before, after)
+			}
+
+		case BulkDueDate:
+			if sameTime(before.DueDate, payload.DueDate) {
+				continue
+			}
+			err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET due_date = $2, updated_at = NOW(), version = version + 1
+				WHERE id = $1 RETURNING `+cardSelectColumns, id, payload.DueDate), &after)
+			if err == nil {
+				err = app.recordCardChanges(tx, boardID, userID, before, after)
+			}
+
+		case BulkArchive:
+			if before.ArchivedAt != nil {
+				continue
+			}
+			err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET archived_at = NOW(), archived_by = $2, updated_at = NOW(), version = version + 1
+				WHERE id = $1 RETURNING `+cardSelectColumns, id, userID), &after)
+			if err == nil {
+				err = app.recordCardEvent(tx, id, boardID, userID, CardEventArchived, "archived_at", nil, after.ArchivedAt)
+			}
+
+		case BulkDelete:
+			_, err = tx.Exec(ctx, `UPDATE cards SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1`, id, userID)
+			if err == nil {
+				err = app.recordCardEvent(tx, id, boardID, userID, CardEventDeleted, "", fiber.Map{"title": before.Title}, nil)
+			}
+		}
+
+		if err != nil {
+			log.Printf("Erro na operação em lote '%s' no card %d: %v", payload.Operation, id, err)
+			return internalError(c, "Erro ao aplicar a operação em lote")
+		}
+
+		switch payload.Operation {
+		case BulkArchive, BulkDelete:
+			compact[before.ColumnID] = true
+			removed[boardID] = append(removed[boardID], id)
+			allRemoved = append(allRemoved, id)
+		default:
+			updated[boardID] = append(updated[boardID], after)
+			allUpdated = append(allUpdated, after)
+		}
+	}
+
+	for columnID := range compact {
+		if err := compactCardPositions(ctx, tx, columnID); err != nil {
+			return internalError(c, "Erro ao reordenar colunas")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar operação em lote")
+	}
+
+	// etiquetas e responsáveis, como no card enviado pelas rotas de um card só
+	details := make([]*Card, len(allUpdated))
+	for i := range allUpdated {
+		details[i] = &allUpdated[i]
+	}
+	if err := app.attachCardDetails(ctx, details); err != nil {
+		log.Printf("Erro ao carregar detalhes dos cards da operação em lote: %v", err)
+	}
+	byID := make(map[int]Card, len(allUpdated))
+	for _, card := range allUpdated {
+		byID[card.ID] = card
+	}
+	for boardID := range updated {
+		for i := range updated[boardID] {
+			updated[boardID][i] = byID[updated[boardID][i].ID]
+		}
+	}
+
+	if target.BoardID != 0 && !seenBoards[target.BoardID] {
+		boardIDs = append(boardIDs, target.BoardID)
+	}
+	for _, boardID := range boardIDs {
+		if len(updated[boardID]) == 0 && len(removed[boardID]) == 0 {
+			continue
+		}
+		app.broadcast(boardID, WsMessage{
+			Type:     "CARDS_BULK_UPDATED",
+			SenderID: userID,
+			Payload: fiber.Map{
+				"operation":        payload.Operation,
+				"cards":            nonNilCards(updated[boardID]),
+				"removed_card_ids": nonNilInts(removed[boardID]),
+			},
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"operation":        payload.Operation,
+		"cards":            allUpdated,
+		"removed_card_ids": allRemoved,
+	})
+}
+
+// cards ativos com suas colunas (e boards), travados para a transação
+func loadBulkCards(ctx context.Context, tx pgx.Tx, cardIDs []int) (map[int]Card, map[int]bulkColumn, error) {
+	rows, err := tx.Query(ctx, `SELECT `+cardSelectColumns+` FROM cards
+		WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id FOR UPDATE`, cardIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	cards := make(map[int]Card, len(cardIDs))
+	columnIDs := make([]int, 0)
+	for rows.Next() {
+		var card Card
+		if err := scanCard(rows, &card); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		cards[card.ID] = card
+		columnIDs = append(columnIDs, card.ColumnID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	rows, err = tx.Query(ctx, `SELECT col.id, col.board_id, col.title FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.id = ANY($1) AND col.deleted_at IS NULL AND b.deleted_at IS NULL`, uniqueInts(columnIDs))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	columns := make(map[int]bulkColumn)
+	for rows.Next() {
+		var id int
+		var col bulkColumn
+		if err := rows.Scan(&id, &col.BoardID, &col.Title); err != nil {
+			return nil, nil, err
+		}
+		columns[id] = col
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	// card em coluna ou board excluído conta como inexistente
+	for id, card := range cards {
+		if _, ok := columns[card.ColumnID]; !ok {
+			delete(cards, id)
+		}
+	}
+	return cards, columns, nil
+}
+
+func missingCardIDs(ids []int, cards map[int]Card) []int {
+	missing := make([]int, 0)
+	for _, id := range ids {
+		if _, ok := cards[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	return missing
+}
+
+// ids sem repetição, na ordem recebida
+func uniqueInts(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	out := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func joinInts(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func nonNilCards(cards []Card) []Card {
+	if cards == nil {
+		return []Card{}
+	}
+	return cards
+}
+
+func nonNilInts(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// estrutura comentario
+type CardComment struct {
+	ID         int       `json:"id" db:"id"`
+	CardID     int       `json:"card_id" db:"card_id"`
+	AuthorID   *string   `json:"author_id,omitempty" db:"author_id"`
+	AuthorName string    `json:"author" db:"author_name"`
+	Section    *string   `json:"section,omitempty" db:"section"`
+	Text       string    `json:"text" db:"content"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// seções do board publico
+var commentSections = map[string]bool{
+	"observacoes": true,
+	"tentativas":  true,
+	"resolucao":   true,
+}
+
+const commentSelectColumns = `id, card_id, author_id::text, author_name, section, content, created_at, updated_at`
+
+func scanCardComment(row pgx.Row, cm *CardComment) error {
+	return row.Scan(&cm.ID, &cm.CardID, &cm.AuthorID, &cm.AuthorName, &cm.Section, &cm.Text, &cm.CreatedAt, &cm.UpdatedAt)
+}
+
+// endpoint comentarios do card
+func (app *App) getCardComments(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do card inválido")
+	}
+	query := `SELECT ` + commentSelectColumns + ` FROM card_comments WHERE card_id = $1`
+	args := []interface{}{cardID}
+	if section := c.Query("section"); section != "" {
+		query += ` AND section = $2`
+		args = append(args, section)
+	}
+	query += ` ORDER BY created_at, id`
+
+	rows, err := app.db.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Erro ao buscar comentários do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao buscar comentários")
+	}
+	defer rows.Close()
+
+	comments := make([]CardComment, 0)
+	for rows.Next() {
+		var cm CardComment
+		if err := scanCardComment(rows, &cm); err != nil {
+			return internalError(c, "Erro ao ler comentário")
+		}
+		comments = append(comments, cm)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Erro ao ler comentários do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao ler comentários")
+	}
+	return c.JSON(comments)
+}
+
+// endpoint criar comentario
+func (app *App) createCardComment(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do card inválido")
+	}
+	userID := c.Locals("userID").(string)
+	boardID := authorizedBoardID(c)
+
+	var payload struct {
+		Text    string  `json:"text"`
+		Section *string `json:"section"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	var v Validator
+	v.Required("text", payload.Text, maxLongTextLength)
+	if payload.Section != nil && !commentSections[*payload.Section] {
+		v.Add("section", "invalid_choice", "valor inválido, use: observacoes, tentativas, resolucao")
+	}
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	authorName := app.getDisplayName(context.Background(), nil, userID)
+	query := `INSERT INTO card_comments (card_id, author_id, author_name, section, content)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING ` + commentSelectColumns
+	var cm CardComment
+	err = scanCardComment(app.db.QueryRow(context.Background(), query, cardID, userID, authorName, payload.Section, payload.Text), &cm)
+	if err != nil {
+		log.Printf("Erro ao criar comentário no card %d: %v", cardID, err)
+		return internalError(c, "Erro ao criar comentário")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "COMMENT_ADDED", Payload: cm, SenderID: userID})
+	return c.Status(201).JSON(cm)
+}
+
+// buscar comentario e validar autoria
+func (app *App) loadOwnComment(c *fiber.Ctx) (int, int, bool) {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		badRequest(c, "ID do card inválido")
+		return 0, 0, false
+	}
+	commentID, err := strconv.Atoi(c.Params("commentId"))
+	if err != nil {
+		badRequest(c, "ID do comentário inválido")
+		return 0, 0, false
+	}
+	userID := c.Locals("userID").(string)
+	boardID := authorizedBoardID(c)
+
+	var authorID *string
+	err = app.db.QueryRow(context.Background(),
+		"SELECT author_id::text FROM card_comments WHERE id = $1 AND card_id = $2", commentID, cardID).Scan(&authorID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			notFound(c, "Comentário não encontrado")
+			return 0, 0, false
+		}
+		internalError(c, "Erro ao buscar comentário")
+		return 0, 0, false
+	}
+	if authorID == nil || *authorID != userID {
+		isAdmin, err := app.isUserAdmin(userID)
+		if err != nil || !isAdmin {
+			accessDenied(c, "Apenas o autor pode alterar este comentário.")
+			return 0, 0, false
+		}
+	}
+	return boardID, commentID, true
+}
+
+// endpoint editar comentario
+func (app *App) updateCardComment(c *fiber.Ctx) error {
+	boardID, commentID, ok := app.loadOwnComment(c)
+	if !ok {
+		return nil
+	}
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		Text string `json:"text"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	payload.Text = strings.TrimSpace(payload.Text)
+	var v Validator
+	v.Required("text", payload.Text, maxLongTextLength)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	query := `UPDATE card_comments SET content = $1, updated_at = NOW()
+			  WHERE id = $2
+			  RETURNING ` + commentSelectColumns
+	var cm CardComment
+	if err := scanCardComment(app.db.QueryRow(context.Background(), query, payload.Text, commentID), &cm); err != nil {
+		log.Printf("Erro ao atualizar comentário %d: %v", commentID, err)
+		return internalError(c, "Erro ao atualizar comentário")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "COMMENT_UPDATED", Payload: cm, SenderID: userID})
+	return c.JSON(cm)
+}
+
+// endpoint deletar comentario
+func (app *App) deleteCardComment(c *fiber.Ctx) error {
+	boardID, commentID, ok := app.loadOwnComment(c)
+	if !ok {
+		return nil
+	}
+	userID := c.Locals("userID").(string)
+	cardID, _ := strconv.Atoi(c.Params("id"))
+
+	if _, err := app.db.Exec(context.Background(), "DELETE FROM card_comments WHERE id = $1", commentID); err != nil {
+		log.Printf("Erro ao deletar comentário %d: %v", commentID, err)
+		return internalError(c, "Erro ao deletar comentário")
+	}
+
+	app.broadcast(boardID, WsMessage{
+		Type:     "COMMENT_DELETED",
+		Payload:  fiber.Map{"comment_id": commentID, "card_id": cardID},
+		SenderID: userID,
+	})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// formato antigo salvo em cards.description
+type legacyComment struct {
+	Text      string `json:"text"`
+	Author    string `json:"author"`
+	Timestamp string `json:"timestamp"`
+}
+
+type legacyDescription struct {
+	Comments    []legacyComment `json:"comments"`
+	Observacoes []legacyComment `json:"observacoes"`
+	Tentativas  []legacyComment `json:"tentativas"`
+	Resolucao   []legacyComment `json:"resolucao"`
+}
+
+// chaves do formato antigo; qualquer outra coisa é descrição comum
+var legacyDescriptionKeys = map[string]bool{
+	"comments":    true,
+	"observacoes": true,
+	"tentativas":  true,
+	"resolucao":   true,
+}
+
+// reconhecer o bloco de comentários salvo pelo frontend antigo; texto puro não é migrado
+func parseLegacyDescription(description string) (legacyDescription, bool) {
+	var desc legacyDescription
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(description), &keys); err != nil || len(keys) == 0 {
+		return desc, false
+	}
+	for key := range keys {
+		if !legacyDescriptionKeys[key] {
+			return desc, false
+		}
+	}
+	if err := json.Unmarshal([]byte(description), &desc); err != nil {
+		return desc, false
+	}
+	return desc, true
+}
+
+// gravar os comentários do bloco antigo em card_comments; comentários iguais já
+// gravados são ignorados, pois clientes antigos reenviam o bloco inteiro a cada edição
+func (app *App) importLegacyComments(ctx context.Context, tx pgx.Tx, cardID int, desc legacyDescription, fallback time.Time, authorIDs map[string]*string) (int, error) {
+	groups := []struct {
+		Section  *string
+		Comments []legacyComment
+	}{
+		{nil, desc.Comments},
+		{strPtr("observacoes"), desc.Observacoes},
+		{strPtr("tentativas"), desc.Tentativas},
+		{strPtr("resolucao"), desc.Resolucao},
+	}
+	total := 0
+	for _, g := range groups {
+		for _, lcm := range g.Comments {
+			text := strings.TrimSpace(lcm.Text)
+			if text == "" {
+				continue
+			}
+			authorID, ok := authorIDs[lcm.Author]
+			if !ok {
+				authorID = app.resolveLegacyAuthor(ctx, tx, lcm.Author)
+				authorIDs[lcm.Author] = authorID
+			}
+			tag, err := tx.Exec(ctx, `
+				INSERT INTO card_comments (card_id, author_id, author_name, section, content, created_at, updated_at)
+				SELECT $1, $2, $3, $4, $5, $6, $6
+				WHERE NOT EXISTS (
+					SELECT 1 FROM card_comments WHERE card_id = $1 AND author_name = $3
+					AND section IS NOT DISTINCT FROM $4 AND content = $5)`,
+				cardID, authorID, lcm.Author, g.Section, text, parseLegacyTimestamp(lcm.Timestamp, fallback))
+			if err != nil {
+				return total, err
+			}
+			total += int(tag.RowsAffected())
+		}
+	}
+	return total, nil
+}
+
+// descrição vinda de um cliente antigo: comentários vão para card_comments e a descrição fica vazia
+func (app *App) absorbLegacyDescription(ctx context.Context, tx pgx.Tx, cardID int, description string) (string, error) {
+	desc, ok := parseLegacyDescription(description)
+	if !ok {
+		return description, nil
+	}
+	if _, err := app.importLegacyComments(ctx, tx, cardID, desc, time.Now(), make(map[string]*string)); err != nil {
+		return description, err
+	}
+	return "", nil
+}
+
+// migrar comentarios da description para card_comments e limpar o bloco da descrição
+func (app *App) migrateDescriptionComments(ctx context.Context) error {
+	rows, err := app.db.Query(ctx, `
+		SELECT id, description, created_at FROM cards
+		WHERE description LIKE '{%'`)
+	if err != nil {
+		return err
+	}
+	type legacyCard struct {
+		ID          int
+		Description string
+		CreatedAt   time.Time
+	}
+	cards := make([]legacyCard, 0)
+	for rows.Next() {
+		var lc legacyCard
+		if err := rows.Scan(&lc.ID, &lc.Description, &lc.CreatedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		cards = append(cards, lc)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	authorIDs := make(map[string]*string)
+	total, migrated := 0, 0
+	for _, lc := range cards {
+		desc, ok := parseLegacyDescription(lc.Description)
+		if !ok {
+			continue
+		}
+		n, err := app.importLegacyComments(ctx, tx, lc.ID, desc, lc.CreatedAt, authorIDs)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE cards SET description = '' WHERE id = $1", lc.ID); err != nil {
+			return err
+		}
+		total += n
+		migrated++
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	log.Printf("Comentários migrados da descrição: %d (em %d cards)", total, migrated)
+	return nil
+}
+
+// resolver autor pelo nome de exibição
+func (app *App) resolveLegacyAuthor(ctx context.Context, tx pgx.Tx, author string) *string {
+	if author == "" || author == "Sistema" {
+		return nil
+	}
+	lookup := author
+	for email, name := range userDisplayNameMap {
+		if name == author {
+			lookup = email
+			break
+		}
+	}
+	var userID string
+	err := tx.QueryRow(ctx,
+		"SELECT id::text FROM auth.users WHERE email = $1 OR raw_user_meta_data->>'username' = $1 LIMIT 1", lookup).Scan(&userID)
+	if err != nil {
+		return nil
+	}
+	return &userID
+}
+
+// timestamps gerados com toLocaleString('pt-BR')
+func parseLegacyTimestamp(value string, fallback time.Time) time.Time {
+	layouts := []string{"02/01/2006, 15:04:05", "02/01/2006 15:04:05", time.RFC3339}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t
+		}
+	}
+	return fallback
+}
+
+func strPtr(s string) *string {
+	return &s
+}
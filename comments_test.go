@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestParseLegacyDescription(t *testing.T) {
+	cases := []struct {
+		name        string
+		description string
+		wantOK      bool
+		wantCount   int
+	}{
+		{"texto puro", "Cliente sem sinal desde ontem", false, 0},
+		{"vazio", "", false, 0},
+		{"json de outro formato", `{"url": "http://exemplo"}`, false, 0},
+		{"json com chave extra", `{"comments": [], "outra": 1}`, false, 0},
+		{"lista json", `[{"text": "a"}]`, false, 0},
+		{"objeto vazio", `{}`, false, 0},
+		{"privado", `{"comments": [{"text": "oi", "author": "Ana", "timestamp": ""}]}`, true, 1},
+		{"público", `{"observacoes": [{"text": "a"}], "tentativas": [{"text": "b"}], "resolucao": []}`, true, 2},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			desc, ok := parseLegacyDescription(tc.description)
+			if ok != tc.wantOK {
+				t.Fatalf("ok = %v, esperado %v", ok, tc.wantOK)
+			}
+			count := len(desc.Comments) + len(desc.Observacoes) + len(desc.Tentativas) + len(desc.Resolucao)
+			if count != tc.wantCount {
+				t.Fatalf("comentários = %d, esperado %d", count, tc.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// pool ou transação
+type rowQuerier interface {
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+}
+
+const cardSelectColumns = `id, column_id, title, COALESCE(description, '') as description,
+	COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
+	due_date, position, created_at, updated_at, completed_at, archived_at, version`
+
+func scanCard(row pgx.Row, card *Card) error {
+	return row.Scan(&card.ID, &card.ColumnID, &card.Title, &card.Description,
+		&card.AssignedTo, &card.Priority, &card.DueDate, &card.Position,
+		&card.CreatedAt, &card.UpdatedAt, &card.CompletedAt, &card.ArchivedAt, &card.Version)
+}
+
+const columnSelectColumns = `id, board_id, title, position, COALESCE(color, '#e4e6ea') as color, version`
+
+func scanColumn(row pgx.Row, col *Column) error {
+	return row.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color, &col.Version)
+}
+
+const ligacaoSelectColumns = `id, name, type, image_url, status, spreadsheet_url, address, end_date, observations, created_at, updated_at, version`
+
+func scanLigacao(row pgx.Row, l *Ligacao) error {
+	return row.Scan(&l.ID, &l.Name, &l.Type, &l.ImageURL, &l.Status, &l.SpreadsheetURL, &l.Address,
+		&l.EndDate, &l.Observations, &l.CreatedAt, &l.UpdatedAt, &l.Version)
+}
+
+const agendaEventSelectColumns = `id, title, description, event_date, color, user_id, created_at, updated_at, version`
+
+func scanAgendaEvent(row pgx.Row, e *AgendaEvent) error {
+	return row.Scan(&e.ID, &e.Title, &e.Description, &e.EventDate, &e.Color, &e.UserID, &e.CreatedAt, &e.UpdatedAt, &e.Version)
+}
+
+const avaliacaoSelectColumns = `id, source, customer_name, review_content, rating, status, review_date, review_url, assigned_to, resolution_notes, created_at, updated_at, version`
+
+func scanAvaliacao(row pgx.Row, a *Avaliacao) error {
+	return row.Scan(&a.ID, &a.Source, &a.CustomerName, &a.ReviewContent, &a.Rating, &a.Status, &a.ReviewDate,
+		&a.ReviewURL, &a.AssignedTo, &a.ResolutionNotes, &a.CreatedAt, &a.UpdatedAt, &a.Version)
+}
+
+// buscar card por id
+func (app *App) fetchCard(q rowQuerier, cardID int) (Card, error) {
+	var card Card
+	err := scanCard(q.QueryRow(context.Background(), `SELECT `+cardSelectColumns+` FROM cards WHERE id = $1`, cardID), &card)
+	return card, err
+}
+
+// versão esperada pelo cliente: If-Match tem prioridade sobre o campo version do corpo; 0 = sem pré-condição
+func requestedVersion(c *fiber.Ctx, bodyVersion int) (int, error) {
+	ifMatch := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if ifMatch == "" || ifMatch == "*" {
+		return bodyVersion, nil
+	}
+	ifMatch = strings.Trim(strings.TrimPrefix(ifMatch, "W/"), `"`)
+	version, err := strconv.Atoi(ifMatch)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("If-Match inválido: %s", c.Get(fiber.HeaderIfMatch))
+	}
+	return version, nil
+}
+
+func setVersionETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, fmt.Sprintf(`"%d"`, version))
+}
+
+// resposta 409 com a cópia atual do servidor
+func versionConflict(c *fiber.Ctx, version int, current interface{}) error {
+	setVersionETag(c, version)
+	return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+		"error":   APIError{Code: ErrCodeConflict, Message: "O registro foi alterado por outra pessoa. Recarregue e tente novamente."},
+		"current": current,
+	})
+}
+
+// If-Match que não pôde ser interpretado
+func invalidPrecondition(c *fiber.Ctx, err error) error {
+	return errorResponse(c, fiber.StatusBadRequest, "invalid_if_match", err.Error(), nil)
+}
+
+// UPDATE condicional sem linhas: 404 se o registro não existe, 409 se a versão mudou
+func conditionalUpdateMiss(c *fiber.Ctx, fetch func() (interface{}, int, error), missing string) error {
+	current, version, err := fetch()
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, missing)
+		}
+		return internalError(c, "Erro ao buscar o registro atual")
+	}
+	return versionConflict(c, version, current)
+}
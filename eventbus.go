@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// canal do LISTEN/NOTIFY
+const boardEventsChannel = "board_events"
+
+// limite do payload do NOTIFY é 8000 bytes; deixa folga para o envelope
+const maxNotifyPayload = 7500
+
+const (
+	BoardEventMessage    = "message"
+	BoardEventDisconnect = "disconnect"
+)
+
+// evento trafegado entre instâncias
+type BoardEvent struct {
+	Origin  string `json:"origin"`
+	Kind    string `json:"kind"`
+	BoardID int    `json:"board_id"`
+	UserID  string `json:"user_id,omitempty"`
+	// disconnect: usuários que continuam conectados
+	KeepUserIDs []string        `json:"keep_user_ids,omitempty"`
+	Code        int             `json:"code,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	Seq         int64           `json:"seq,omitempty"`
+	Message     json.RawMessage `json:"message,omitempty"`
+}
+
+// barramento de eventos entre réplicas da API
+type EventBus interface {
+	Publish(ctx context.Context, ev BoardEvent) error
+	Subscribe(handler func(BoardEvent))
+	Close() error
+}
+
+// barramento em memória: um único processo, útil também em testes
+type MemoryEventBus struct {
+	mu       sync.RWMutex
+	handlers []func(BoardEvent)
+}
+
+func NewMemoryEventBus() *MemoryEventBus {
+	return &MemoryEventBus{}
+}
+
+func (b *MemoryEventBus) Publish(ctx context.Context, ev BoardEvent) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(ev)
+	}
+	return nil
+}
+
+func (b *MemoryEventBus) Subscribe(handler func(BoardEvent)) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+}
+
+func (b *MemoryEventBus) Close() error {
+	return nil
+}
+
+type execer interface {
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+}
+
+// barramento via postgres LISTEN/NOTIFY
+type PgEventBus struct {
+	publisher  execer
+	connConfig *pgx.ConnConfig
+	mu         sync.RWMutex
+	handlers   []func(BoardEvent)
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+func NewPgEventBus(publisher execer, connConfig *pgx.ConnConfig) *PgEventBus {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := &PgEventBus{
+		publisher:  publisher,
+		connConfig: connConfig,
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	go b.listen(ctx)
+	return b
+}
+
+func (b *PgEventBus) Publish(ctx context.Context, ev BoardEvent) error {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		// grande demais para o NOTIFY: as outras réplicas pedem recarga do board
+		ev.Message, _ = json.Marshal(WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil})
+		if payload, err = json.Marshal(ev); err != nil {
+			return err
+		}
+	}
+	_, err = b.publisher.Exec(ctx, "SELECT pg_notify($1, $2)", boardEventsChannel, string(payload))
+	return err
+}
+
+func (b *PgEventBus) Subscribe(handler func(BoardEvent)) {
+	b.mu.Lock()
+	b.handlers = append(b.handlers, handler)
+	b.mu.Unlock()
+}
+
+func (b *PgEventBus) Close() error {
+	b.cancel()
+	<-b.done
+	return nil
+}
+
+// conexão dedicada ao LISTEN, reconectando com backoff
+func (b *PgEventBus) listen(ctx context.Context) {
+	defer close(b.done)
+	backoff := time.Second
+	for {
+		err := b.listenOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("EventBus: conexão LISTEN perdida: %v (nova tentativa em %s)", err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *PgEventBus) listenOnce(ctx context.Context) error {
+	conn, err := pgx.ConnectConfig(ctx, b.connConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+boardEventsChannel); err != nil {
+		return err
+	}
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var ev BoardEvent
+		if err := json.Unmarshal([]byte(notification.Payload), &ev); err != nil {
+			log.Printf("EventBus: payload inválido: %v", err)
+			continue
+		}
+		b.mu.RLock()
+		for _, handler := range b.handlers {
+			handler(ev)
+		}
+		b.mu.RUnlock()
+	}
+}
+
+// escolher o barramento via EVENT_BUS (memory|postgres)
+func (app *App) setupEventBus() error {
+	app.instanceID = newInstanceID()
+	switch os.Getenv("EVENT_BUS") {
+	case "", "memory":
+		app.bus = NewMemoryEventBus()
+	case "postgres":
+		connConfig := app.db.Config().ConnConfig.Copy()
+		// LISTEN não funciona atrás de pooler em modo transação; permite URL direta
+		if url := os.Getenv("EVENT_BUS_DATABASE_URL"); url != "" {
+			cfg, err := pgx.ParseConfig(url)
+			if err != nil {
+				return fmt.Errorf("EVENT_BUS_DATABASE_URL inválida: %w", err)
+			}
+			connConfig = cfg
+		}
+		app.bus = NewPgEventBus(app.db, connConfig)
+	default:
+		return fmt.Errorf("EVENT_BUS desconhecido: %s", os.Getenv("EVENT_BUS"))
+	}
+	app.bus.Subscribe(app.relayBoardEvent)
+	return nil
+}
+
+// repassar eventos de outras instâncias aos sockets locais
+func (app *App) relayBoardEvent(ev BoardEvent) {
+	// a própria instância já entregou localmente
+	if ev.Origin == app.instanceID {
+		return
+	}
+	switch ev.Kind {
+	case BoardEventMessage:
+		app.hub.Broadcast(ev.BoardID, ev.Seq, ev.Message)
+	case BoardEventDisconnect:
+		if len(ev.KeepUserIDs) > 0 {
+			app.hub.DisconnectExcept(ev.BoardID, ev.KeepUserIDs, ev.Code, ev.Reason)
+		} else {
+			app.hub.Disconnect(ev.BoardID, ev.UserID, ev.Code, ev.Reason)
+		}
+	case BoardEventPresence:
+		app.applyRemotePresence(ev)
+	}
+}
+
+// publicar no barramento; falhas só são logadas
+func (app *App) publishBoardEvent(ev BoardEvent) {
+	if app.bus == nil {
+		return
+	}
+	ev.Origin = app.instanceID
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := app.bus.Publish(ctx, ev); err != nil {
+		log.Printf("EventBus: erro ao publicar evento do board %d: %v", ev.BoardID, err)
+	}
+}
+
+func newInstanceID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// duas instâncias da API ligadas pelo mesmo barramento em memória
+func newBusTestApps(t *testing.T) (a, b *App, sa, sb *hubTestServer) {
+	t.Helper()
+	bus := NewMemoryEventBus()
+	sa, sb = newHubTestServer(t), newHubTestServer(t)
+	a = &App{hub: sa.hub, bus: bus, instanceID: "a", presence: NewPresenceRegistry()}
+	b = &App{hub: sb.hub, bus: bus, instanceID: "b", presence: NewPresenceRegistry()}
+	bus.Subscribe(a.relayBoardEvent)
+	bus.Subscribe(b.relayBoardEvent)
+	return a, b, sa, sb
+}
+
+func readWsMessage(t *testing.T, conn *fastws.Conn) WsMessage {
+	t.Helper()
+	raw := readText(t, conn)
+	var msg WsMessage
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatalf("mensagem inválida %q: %v", raw, err)
+	}
+	return msg
+}
+
+func TestMemoryEventBusRelaysToOtherInstance(t *testing.T) {
+	a, _, sa, sb := newBusTestApps(t)
+	local := sa.dial(t, 1, "local")
+	remote := sb.dial(t, 1, "remote")
+	otherBoard := sb.dial(t, 2, "other")
+
+	seq := int64(6)
+	appendEvent := func(int, WsMessage) (int64, error) {
+		seq++
+		return seq, nil
+	}
+	a.emitBoardEvent(1, WsMessage{Type: "CARD_UPDATED", SenderID: "u1"}, appendEvent)
+	a.emitBoardEvent(1, WsMessage{Type: "CARD_DELETED", SenderID: "u1"}, appendEvent)
+	a.emitBoardEvent(2, WsMessage{Type: "CARD_CREATED"}, appendEvent)
+
+	// a origem entrega uma vez só (o relay ignora o próprio Origin);
+	// a outra instância recebe os mesmos seq, na ordem
+	for _, conn := range []*fastws.Conn{local, remote} {
+		for _, want := range []struct {
+			seq int64
+			typ string
+		}{{7, "CARD_UPDATED"}, {8, "CARD_DELETED"}} {
+			msg := readWsMessage(t, conn)
+			if msg.Seq != want.seq || msg.Type != want.typ || msg.SenderID != "u1" {
+				t.Fatalf("esperado %s seq %d, veio %+v", want.typ, want.seq, msg)
+			}
+		}
+	}
+	if msg := readWsMessage(t, otherBoard); msg.Type != "CARD_CREATED" || msg.Seq != 9 {
+		t.Fatalf("board 2 recebeu %+v", msg)
+	}
+}
+
+func TestMemoryEventBusRelaysPresence(t *testing.T) {
+	a, b, _, sb := newBusTestApps(t)
+	remote := sb.dial(t, 1, "remote")
+
+	cardID := 10
+	a.presence.local[1] = map[*wsClient]*presenceConn{
+		{boardID: 1, userID: "u1"}: {User: UserSummary{ID: "u1", Name: "Usuário 1"}, EditingCardID: &cardID},
+	}
+	a.publishLocalPresence(1)
+
+	msg := readWsMessage(t, remote)
+	if msg.Type != "PRESENCE_CHANGED" {
+		t.Fatalf("esperado PRESENCE_CHANGED, veio %+v", msg)
+	}
+	users := b.presenceSnapshot(1)
+	if len(users) != 1 || users[0].ID != "u1" || len(users[0].EditingCardIDs) != 1 || users[0].EditingCardIDs[0] != cardID {
+		t.Fatalf("presença remota = %+v", users)
+	}
+	// a própria instância não guarda o que publicou como presença remota
+	if len(a.presence.remote[1]) != 0 {
+		t.Fatalf("presença da própria origem registrada como remota: %+v", a.presence.remote[1])
+	}
+
+	// lista vazia remove a presença daquela origem
+	a.presence.local[1] = nil
+	a.publishLocalPresence(1)
+	readWsMessage(t, remote)
+	if users := b.presenceSnapshot(1); len(users) != 0 {
+		t.Fatalf("presença não removida: %+v", users)
+	}
+}
+
+// guarda o payload do pg_notify em vez de falar com o banco
+type notifyRecorder struct {
+	payloads []string
+}
+
+func (r *notifyRecorder) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	r.payloads = append(r.payloads, args[1].(string))
+	return pgconn.CommandTag{}, nil
+}
+
+func TestPgEventBusDowngradesOversizedPayload(t *testing.T) {
+	rec := &notifyRecorder{}
+	bus := &PgEventBus{publisher: rec}
+
+	small, _ := json.Marshal(WsMessage{Type: "CARD_UPDATED", Payload: map[string]string{"title": "curto"}})
+	large, _ := json.Marshal(WsMessage{Type: "CARD_UPDATED", Payload: map[string]string{"description": strings.Repeat("x", maxNotifyPayload)}})
+	for _, message := range [][]byte{small, large} {
+		if err := bus.Publish(context.Background(), BoardEvent{Origin: "a", Kind: BoardEventMessage, BoardID: 1, Seq: 5, Message: message}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(rec.payloads) != 2 {
+		t.Fatalf("esperados 2 NOTIFY, vieram %d", len(rec.payloads))
+	}
+	for i, payload := range rec.payloads {
+		if len(payload) > maxNotifyPayload {
+			t.Fatalf("payload %d com %d bytes passa do limite", i, len(payload))
+		}
+		var ev BoardEvent
+		if err := json.Unmarshal([]byte(payload), &ev); err != nil {
+			t.Fatal(err)
+		}
+		if ev.Origin != "a" || ev.BoardID != 1 || ev.Seq != 5 {
+			t.Fatalf("envelope alterado: %+v", ev)
+		}
+		var msg WsMessage
+		if err := json.Unmarshal(ev.Message, &msg); err != nil {
+			t.Fatal(err)
+		}
+		want := "CARD_UPDATED"
+		if i == 1 {
+			// grande demais: as outras réplicas só recebem o pedido de recarga
+			want = "BOARD_STATE_UPDATED"
+		}
+		if msg.Type != want {
+			t.Fatalf("payload %d: esperado %s, veio %s", i, want, msg.Type)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// tipos de evento do historico
+const (
+	CardEventCreated          = "created"
+	CardEventFieldChanged     = "field_changed"
+	CardEventMoved            = "moved"
+	CardEventAssigned         = "assigned"
+	CardEventCompletedSet     = "completed_set"
+	CardEventCompletedCleared = "completed_cleared"
+	CardEventDeleted          = "deleted"
+	CardEventRestored         = "restored"
+	CardEventArchived         = "archived"
+	CardEventUnarchived       = "unarchived"
+)
+
+// estrutura evento de card
+type CardEvent struct {
+	ID        int64           `json:"id" db:"id"`
+	CardID    int             `json:"card_id" db:"card_id"`
+	BoardID   int             `json:"board_id" db:"board_id"`
+	ActorID   *string         `json:"actor_id,omitempty" db:"actor_id"`
+	ActorName string          `json:"actor_name,omitempty" db:"actor_name"`
+	EventType string          `json:"event_type" db:"event_type"`
+	Field     *string         `json:"field,omitempty" db:"field"`
+	OldValue  json.RawMessage `json:"old_value,omitempty" db:"old_value"`
+	NewValue  json.RawMessage `json:"new_value,omitempty" db:"new_value"`
+	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+}
+
+// gravar evento dentro da transação
+func (app *App) recordCardEvent(tx pgx.Tx, cardID, boardID int, actorID, eventType, field string, oldValue, newValue interface{}) error {
+	var fieldArg *string
+	if field != "" {
+		fieldArg = &field
+	}
+	oldJSON, err := marshalEventValue(oldValue)
+	if err != nil {
+		return err
+	}
+	newJSON, err := marshalEventValue(newValue)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO card_events (card_id, board_id, actor_id, event_type, field, old_value, new_value)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	_, err = tx.Exec(context.Background(), query, cardID, boardID, actorID, eventType, fieldArg, oldJSON, newJSON)
+	return err
+}
+
+func marshalEventValue(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// registrar diferenças entre versões do card; responsáveis ficam com updateCardAssignees
+func (app *App) recordCardChanges(tx pgx.Tx, boardID int, actorID string, before, after Card) error {
+	type change struct {
+		field    string
+		old, new interface{}
+	}
+	changes := make([]change, 0)
+	if before.Title != after.Title {
+		changes = append(changes, change{"title", before.Title, after.Title})
+	}
+	if before.Description != after.Description {
+		changes = append(changes, change{"description", before.Description, after.Description})
+	}
+	if before.Priority != after.Priority {
+		changes = append(changes, change{"priority", before.Priority, after.Priority})
+	}
+	if !sameTime(before.DueDate, after.DueDate) {
+		changes = append(changes, change{"due_date", before.DueDate, after.DueDate})
+	}
+	for _, ch := range changes {
+		if err := app.recordCardEvent(tx, before.ID, boardID, actorID, CardEventFieldChanged, ch.field, ch.old, ch.new); err != nil {
+			return err
+		}
+	}
+
+	if before.ColumnID != after.ColumnID {
+		if err := app.recordCardEvent(tx, before.ID, boardID, actorID, CardEventMoved, "column_id",
+			fiber.Map{"column_id": before.ColumnID}, fiber.Map{"column_id": after.ColumnID}); err != nil {
+			return err
+		}
+	}
+	return app.recordCompletionChange(tx, before.ID, boardID, actorID, before.CompletedAt, after.CompletedAt)
+}
+
+// registrar mudança de completed_at
+func (app *App) recordCompletionChange(tx pgx.Tx, cardID, boardID int, actorID string, before, after *time.Time) error {
+	switch {
+	case before == nil && after != nil:
+		return app.recordCardEvent(tx, cardID, boardID, actorID, CardEventCompletedSet, "completed_at", nil, after)
+	case before != nil && after == nil:
+		return app.recordCardEvent(tx, cardID, boardID, actorID, CardEventCompletedCleared, "completed_at", before, nil)
+	case before != nil && after != nil && !before.Equal(*after):
+		return app.recordCardEvent(tx, cardID, boardID, actorID, CardEventCompletedSet, "completed_at", before, after)
+	}
+	return nil
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// endpoint historico do card
+func (app *App) getCardHistory(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do card inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	// cards excluídos continuam com histórico, então o board vem do próprio log
+	boardID, err := app.getBoardIDFromCard(cardID)
+	if err != nil {
+		err = app.db.QueryRow(context.Background(),
+			"SELECT board_id FROM card_events WHERE card_id = $1 ORDER BY id DESC LIMIT 1", cardID).Scan(&boardID)
+		if err != nil {
+			return notFound(c, "Card não encontrado")
+		}
+	}
+	if hasPermission, err := app.checkBoardPermission(userID, boardID); err != nil || !hasPermission {
+		return forbidden(c)
+	}
+	// só os eventos do board atual: após mudar de board, o histórico do board de
+	// origem (comentários, valores antigos) não vaza para quem só vê o destino
+	return app.listCardEvents(c, "e.card_id = $3 AND e.board_id = $4", cardID, boardID)
+}
+
+// endpoint atividade do board
+func (app *App) getBoardActivity(c *fiber.Ctx) error {
+	return app.listCardEvents(c, "e.board_id = $3", authorizedBoardID(c))
+}
+
+// listar eventos com paginação por cursor (id decrescente); o filtro usa $3 em diante
+func (app *App) listCardEvents(c *fiber.Ctx, filter string, filterArgs ...interface{}) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "50"))
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	cursor, _ := strconv.ParseInt(c.Query("cursor"), 10, 64)
+
+	query := `
+		SELECT e.id, e.card_id, e.board_id, e.actor_id::text, COALESCE(u.email, ''),
+			   COALESCE(u.raw_user_meta_data->>'username', u.email, ''),
+			   e.event_type, e.field, e.old_value, e.new_value, e.created_at
+		FROM card_events e
+		LEFT JOIN auth.users u ON u.id = e.actor_id
+		WHERE ` + filter + ` AND ($1 = 0 OR e.id < $1)
+		ORDER BY e.id DESC
+		LIMIT $2`
+	rows, err := app.db.Query(context.Background(), query, append([]interface{}{cursor, limit + 1}, filterArgs...)...)
+	if err != nil {
+		log.Printf("Erro ao buscar histórico: %v", err)
+		return internalError(c, "Erro ao buscar histórico")
+	}
+	defer rows.Close()
+
+	events := make([]CardEvent, 0, limit)
+	for rows.Next() {
+		var ev CardEvent
+		var actorEmail, actorUsername string
+		if err := rows.Scan(&ev.ID, &ev.CardID, &ev.BoardID, &ev.ActorID, &actorEmail, &actorUsername,
+			&ev.EventType, &ev.Field, &ev.OldValue, &ev.NewValue, &ev.CreatedAt); err != nil {
+			return internalError(c, "Erro ao ler evento do histórico")
+		}
+		if displayName, ok := userDisplayNameMap[actorEmail]; ok {
+			ev.ActorName = displayName
+		} else {
+			ev.ActorName = actorUsername
+		}
+		events = append(events, ev)
+	}
+
+	var nextCursor *int64
+	if len(events) > limit {
+		events = events[:limit]
+		next := events[limit-1].ID
+		nextCursor = &next
+	}
+	return c.JSON(fiber.Map{"events": events, "next_cursor": nextCursor})
+}
+
+// board do card dentro da transação
+func cardBoardIDTx(tx pgx.Tx, cardID int) (int, error) {
+	var boardID int
+	err := tx.QueryRow(context.Background(),
+		"SELECT col.board_id FROM cards ca JOIN columns col ON ca.column_id = col.id WHERE ca.id = $1", cardID).Scan(&boardID)
+	return boardID, err
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/gofiber/websocket/v2"
+)
+
+const (
+	// tempo máximo para escrever uma mensagem
+	wsWriteWait = 10 * time.Second
+	// tempo máximo sem pong antes de considerar a conexão morta
+	wsPongWait = 60 * time.Second
+	// intervalo dos pings, precisa ser menor que wsPongWait
+	wsPingPeriod = (wsPongWait * 9) / 10
+	// tamanho máximo de mensagem vinda do cliente
+	wsMaxMessageSize = 4096
+	// fila de envio por conexão; cheia = cliente lento e desconectado
+	wsSendBufferSize = 64
+)
+
+// conexão registrada no hub
+type wsClient struct {
+	hub       *Hub
+	conn      *websocket.Conn
+	boardID   int
+	userID    string
+	send      chan wsOutbound
+	closeCode int
+	closeText string
+	// último seq entregue pelo replay; gravado no OnRegister, antes do writer iniciar
+	replayedSeq int64
+}
+
+// mensagem na fila de um cliente; seq 0 = evento fora do log (presença)
+type wsOutbound struct {
+	seq  int64
+	data []byte
+}
+
+// ganchos do ciclo de vida de uma conexão
+type wsHandlers struct {
+	// chamado após o registro e antes do writer iniciar; pode escrever direto na conexão
+	OnRegister func(*wsClient) error
+	OnMessage  func(*wsClient, []byte)
+	OnClose    func(*wsClient)
+}
+
+type hubMessage struct {
+	boardID int
+	wsOutbound
+}
+
+type hubDisconnect struct {
+	boardID int
+	userID  string
+	keep    []string
+	code    int
+	reason  string
+}
+
+// hub de websockets: só a goroutine run acessa o mapa de clientes
+type Hub struct {
+	boards     map[int]map[*wsClient]bool
+	register   chan *wsClient
+	unregister chan *wsClient
+	messages   chan hubMessage
+	disconnect chan hubDisconnect
+	quit       chan struct{}
+	done       chan struct{}
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		boards:     make(map[int]map[*wsClient]bool),
+		register:   make(chan *wsClient),
+		unregister: make(chan *wsClient),
+		messages:   make(chan hubMessage, 256),
+		disconnect: make(chan hubDisconnect),
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// loop principal do hub
+func (h *Hub) Run() {
+	defer close(h.done)
+	for {
+		select {
+		case client := <-h.register:
+			if h.boards[client.boardID] == nil {
+				h.boards[client.boardID] = make(map[*wsClient]bool)
+			}
+			h.boards[client.boardID][client] = true
+		case client := <-h.unregister:
+			h.remove(client, websocket.CloseNormalClosure, "")
+		case msg := <-h.messages:
+			for client := range h.boards[msg.boardID] {
+				select {
+				case client.send <- msg.wsOutbound:
+				default:
+					log.Printf("WebSocket: cliente lento desconectado do board %d (user %s)", client.boardID, client.userID)
+					h.remove(client, websocket.ClosePolicyViolation, "cliente lento")
+				}
+			}
+		case req := <-h.disconnect:
+			for client := range h.boards[req.boardID] {
+				if (req.userID == "" || client.userID == req.userID) && !slices.Contains(req.keep, client.userID) {
+					h.remove(client, req.code, req.reason)
+				}
+			}
+		case <-h.quit:
+			for _, clients := range h.boards {
+				for client := range clients {
+					h.remove(client, websocket.CloseGoingAway, "servidor encerrando")
+				}
+			}
+			return
+		}
+	}
+}
+
+// remover cliente e fechar sua fila; o writer envia o close frame
+func (h *Hub) remove(client *wsClient, code int, reason string) {
+	clients, ok := h.boards[client.boardID]
+	if !ok || !clients[client] {
+		return
+	}
+	delete(clients, client)
+	if len(clients) == 0 {
+		delete(h.boards, client.boardID)
+	}
+	client.closeCode = code
+	client.closeText = reason
+	close(client.send)
+}
+
+// enviar mensagem a todos os clientes do board; seq é o do log do board (0 se não houver)
+func (h *Hub) Broadcast(boardID int, seq int64, data []byte) {
+	select {
+	case h.messages <- hubMessage{boardID: boardID, wsOutbound: wsOutbound{seq: seq, data: data}}:
+	case <-h.quit:
+	}
+}
+
+// desconectar clientes de um board; userID vazio desconecta todos
+func (h *Hub) Disconnect(boardID int, userID string, code int, reason string) {
+	select {
+	case h.disconnect <- hubDisconnect{boardID: boardID, userID: userID, code: code, reason: reason}:
+	case <-h.quit:
+	}
+}
+
+// desconectar do board todos os clientes, menos os usuários em keepUserIDs
+func (h *Hub) DisconnectExcept(boardID int, keepUserIDs []string, code int, reason string) {
+	select {
+	case h.disconnect <- hubDisconnect{boardID: boardID, keep: keepUserIDs, code: code, reason: reason}:
+	case <-h.quit:
+	}
+}
+
+// encerrar o hub fechando todas as conexões
+func (h *Hub) Shutdown(ctx context.Context) error {
+	select {
+	case <-h.quit:
+	default:
+		close(h.quit)
+	}
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// atender uma conexão até ela terminar; bloqueia a goroutine do handler
+func (h *Hub) Serve(conn *websocket.Conn, boardID int, userID string, handlers wsHandlers) {
+	client := &wsClient{
+		hub:     h,
+		conn:    conn,
+		boardID: boardID,
+		userID:  userID,
+		send:    make(chan wsOutbound, wsSendBufferSize),
+	}
+	select {
+	case h.register <- client:
+	case <-h.quit:
+		conn.Close()
+		return
+	}
+
+	writerDone := make(chan struct{})
+	if handlers.OnRegister != nil {
+		if err := handlers.OnRegister(client); err != nil {
+			log.Printf("WebSocket: erro ao iniciar conexão do board %d: %v", boardID, err)
+			conn.Close()
+		}
+	}
+	go client.writePump(writerDone)
+	client.readPump(handlers.OnMessage)
+
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
+	<-writerDone
+	if handlers.OnClose != nil {
+		handlers.OnClose(client)
+	}
+}
+
+// escrever direto na conexão; só antes do writer iniciar (OnRegister)
+func (c *wsClient) writeDirect(data []byte) error {
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+// ler mensagens do cliente e manter o deadline via pong
+func (c *wsClient) readPump(onMessage func(*wsClient, []byte)) {
+	c.conn.SetReadLimit(wsMaxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if onMessage != nil {
+			onMessage(c, data)
+		}
+	}
+}
+
+// única goroutine que escreve na conexão
+func (c *wsClient) writePump(done chan struct{}) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+		close(done)
+	}()
+	for {
+		select {
+		case msg, ok := <-c.send:
+			if ok && msg.seq != 0 && msg.seq <= c.replayedSeq {
+				// registrado antes do replay: o evento já foi entregue por ele
+				continue
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(c.closeCode, c.closeText))
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, msg.data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
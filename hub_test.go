@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	fastws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// servidor fiber em memória com o hub atendendo /ws/:board?user=
+type hubTestServer struct {
+	hub        *Hub
+	addr       string
+	registered chan string
+	closed     chan string
+	// simula o replay feito no OnRegister
+	replayedSeq int64
+}
+
+func newHubTestServer(t *testing.T) *hubTestServer {
+	t.Helper()
+	s := &hubTestServer{
+		hub:        NewHub(),
+		registered: make(chan string, 16),
+		closed:     make(chan string, 16),
+	}
+	go s.hub.Run()
+
+	fiberApp := fiber.New(fiber.Config{DisableStartupMessage: true})
+	fiberApp.Get("/ws/:board", websocket.New(func(conn *websocket.Conn) {
+		boardID, _ := strconv.Atoi(conn.Params("board"))
+		s.hub.Serve(conn, boardID, conn.Query("user"), wsHandlers{
+			OnRegister: func(c *wsClient) error {
+				c.replayedSeq = s.replayedSeq
+				s.registered <- c.userID
+				return nil
+			},
+			OnClose: func(c *wsClient) {
+				s.closed <- c.userID
+			},
+		})
+	}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.addr = ln.Addr().String()
+	go fiberApp.Listener(ln)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s.hub.Shutdown(ctx)
+		fiberApp.ShutdownWithTimeout(5 * time.Second)
+	})
+	return s
+}
+
+// conectar e esperar o registro no hub
+func (s *hubTestServer) dial(t *testing.T, boardID int, userID string) *fastws.Conn {
+	t.Helper()
+	conn, _, err := fastws.DefaultDialer.Dial(fmt.Sprintf("ws://%s/ws/%d?user=%s", s.addr, boardID, userID), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	s.waitFor(t, s.registered, userID)
+	return conn
+}
+
+func (s *hubTestServer) waitFor(t *testing.T, ch chan string, userID string) {
+	t.Helper()
+	select {
+	case got := <-ch:
+		if got != userID {
+			t.Fatalf("esperado %s, veio %s", userID, got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout esperando %s", userID)
+	}
+}
+
+func readText(t *testing.T, conn *fastws.Conn) string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// ler até o close frame e devolver o código
+func readCloseCode(t *testing.T, conn *fastws.Conn) int {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			var closeErr *fastws.CloseError
+			if !errors.As(err, &closeErr) {
+				t.Fatalf("esperado close frame, veio %v", err)
+			}
+			return closeErr.Code
+		}
+	}
+}
+
+func TestHubRegisterAndUnregister(t *testing.T) {
+	s := newHubTestServer(t)
+	a := s.dial(t, 1, "a")
+	b := s.dial(t, 1, "b")
+
+	a.Close()
+	s.waitFor(t, s.closed, "a")
+
+	// depois do unregister só b recebe
+	s.hub.Broadcast(1, 0, []byte("depois"))
+	if got := readText(t, b); got != "depois" {
+		t.Fatalf("b recebeu %q", got)
+	}
+	select {
+	case user := <-s.closed:
+		t.Fatalf("conexão %s encerrada sem pedido", user)
+	default:
+	}
+}
+
+func TestHubBroadcastFanOut(t *testing.T) {
+	s := newHubTestServer(t)
+	a := s.dial(t, 1, "a")
+	b := s.dial(t, 1, "b")
+	other := s.dial(t, 2, "c")
+
+	for i := 0; i < 10; i++ {
+		s.hub.Broadcast(1, 0, []byte(strconv.Itoa(i)))
+	}
+	s.hub.Broadcast(2, 0, []byte("board2"))
+
+	// cada cliente do board recebe tudo, na ordem
+	for _, conn := range []*fastws.Conn{a, b} {
+		for i := 0; i < 10; i++ {
+			if got := readText(t, conn); got != strconv.Itoa(i) {
+				t.Fatalf("mensagem %d: veio %q", i, got)
+			}
+		}
+	}
+	if got := readText(t, other); got != "board2" {
+		t.Fatalf("board 2 recebeu %q", got)
+	}
+}
+
+func TestHubDropsEventsDeliveredByReplay(t *testing.T) {
+	s := newHubTestServer(t)
+	s.replayedSeq = 2
+	conn := s.dial(t, 1, "a")
+
+	// seq 1 e 2 já foram no replay; mensagens sem seq (presença) sempre passam
+	s.hub.Broadcast(1, 1, []byte("1"))
+	s.hub.Broadcast(1, 2, []byte("2"))
+	s.hub.Broadcast(1, 0, []byte("presença"))
+	s.hub.Broadcast(1, 3, []byte("3"))
+	for _, want := range []string{"presença", "3"} {
+		if got := readText(t, conn); got != want {
+			t.Fatalf("esperado %q, veio %q", want, got)
+		}
+	}
+}
+
+// o primeiro evento demora entre receber o seq e ser enfileirado; sem o lock do
+// board o segundo passaria na frente e o cliente veria 2 antes de 1
+func TestEmitBoardEventKeepsSeqOrder(t *testing.T) {
+	s := newHubTestServer(t)
+	conn := s.dial(t, 1, "a")
+	app := &App{hub: s.hub}
+
+	var mu sync.Mutex
+	var lastSeq int64
+	secondStarted := make(chan struct{})
+	appendEvent := func(boardID int, message WsMessage) (int64, error) {
+		mu.Lock()
+		lastSeq++
+		seq := lastSeq
+		mu.Unlock()
+		if seq == 1 {
+			select {
+			case <-secondStarted:
+			case <-time.After(100 * time.Millisecond):
+			}
+		} else {
+			close(secondStarted)
+		}
+		return seq, nil
+	}
+
+	first := make(chan struct{})
+	go func() {
+		app.emitBoardEvent(1, WsMessage{Type: "FIRST"}, appendEvent)
+		close(first)
+	}()
+	// garante que o primeiro já pegou o lock antes do segundo tentar
+	time.Sleep(20 * time.Millisecond)
+	app.emitBoardEvent(1, WsMessage{Type: "SECOND"}, appendEvent)
+	<-first
+
+	for _, want := range []int64{1, 2} {
+		var msg WsMessage
+		if err := json.Unmarshal([]byte(readText(t, conn)), &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Seq != want {
+			t.Fatalf("esperado seq %d, veio %d (%s)", want, msg.Seq, msg.Type)
+		}
+	}
+}
+
+func TestHubEvictsSlowConsumer(t *testing.T) {
+	s := newHubTestServer(t)
+	slow := s.dial(t, 1, "slow")
+	fast := s.dial(t, 1, "fast")
+
+	// mensagens grandes enchem o buffer do socket do cliente que não lê,
+	// o writer dele trava e a fila de envio estoura; o rápido lê cada uma
+	payload := bytes.Repeat([]byte("x"), 256*1024)
+	for i := 0; i < 4*wsSendBufferSize; i++ {
+		s.hub.Broadcast(1, 0, payload)
+		fast.SetReadDeadline(time.Now().Add(5 * time.Second))
+		if _, data, err := fast.ReadMessage(); err != nil || len(data) != len(payload) {
+			t.Fatalf("cliente rápido na mensagem %d: %v", i, err)
+		}
+	}
+
+	if code := readCloseCode(t, slow); code != websocket.ClosePolicyViolation {
+		t.Fatalf("código de close = %d, esperado %d", code, websocket.ClosePolicyViolation)
+	}
+	s.waitFor(t, s.closed, "slow")
+
+	// o cliente rápido continua registrado
+	s.hub.Broadcast(1, 0, []byte("fim"))
+	if got := readText(t, fast); got != "fim" {
+		t.Fatalf("cliente rápido recebeu %q", got)
+	}
+}
+
+func TestHubShutdownClosesConnections(t *testing.T) {
+	s := newHubTestServer(t)
+	a := s.dial(t, 1, "a")
+	b := s.dial(t, 2, "b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, conn := range []*fastws.Conn{a, b} {
+		if code := readCloseCode(t, conn); code != websocket.CloseGoingAway {
+			t.Fatalf("código de close = %d, esperado %d", code, websocket.CloseGoingAway)
+		}
+	}
+	// os handlers terminam mesmo com o hub parado
+	for i := 0; i < 2; i++ {
+		select {
+		case <-s.closed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("handler não terminou após o shutdown")
+		}
+	}
+	// chamadas depois do shutdown não bloqueiam
+	s.hub.Broadcast(1, 0, []byte("ignorada"))
+	s.hub.Disconnect(1, "", websocket.CloseNormalClosure, "")
+	if err := s.hub.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHubDisconnectExceptKeepsMembers(t *testing.T) {
+	s := newHubTestServer(t)
+	owner := s.dial(t, 1, "owner")
+	outsider := s.dial(t, 1, "outsider")
+	otherBoard := s.dial(t, 2, "outsider")
+
+	s.hub.DisconnectExcept(1, []string{"owner"}, wsCloseAccessRevoked, "acesso revogado")
+	if code := readCloseCode(t, outsider); code != wsCloseAccessRevoked {
+		t.Fatalf("código de close = %d, esperado %d", code, wsCloseAccessRevoked)
+	}
+	s.waitFor(t, s.closed, "outsider")
+
+	// o dono continua no board 1 e o mesmo usuário continua em outros boards
+	s.hub.Broadcast(1, 0, []byte("board 1"))
+	s.hub.Broadcast(2, 0, []byte("board 2"))
+	if got := readText(t, owner); got != "board 1" {
+		t.Fatalf("dono recebeu %q", got)
+	}
+	if got := readText(t, otherBoard); got != "board 2" {
+		t.Fatalf("board 2 recebeu %q", got)
+	}
+}
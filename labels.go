@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// estrutura etiqueta do board
+type Label struct {
+	ID        int       `json:"id" db:"id"`
+	BoardID   int       `json:"board_id" db:"board_id"`
+	Name      string    `json:"name" db:"name"`
+	Color     string    `json:"color" db:"color"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+const labelSelectColumns = `id, board_id, name, color, created_at`
+
+func scanLabel(row pgx.Row, l *Label) error {
+	return row.Scan(&l.ID, &l.BoardID, &l.Name, &l.Color, &l.CreatedAt)
+}
+
+func validateLabel(l *Label) error {
+	var v Validator
+	l.Name = strings.TrimSpace(l.Name)
+	v.Required("name", l.Name, maxColumnTitleLength)
+	v.HexColor("color", l.Color, true)
+	return v.Err()
+}
+
+// nome repetido no mesmo board (índice único em lower(name))
+func labelNameTaken(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func labelNameTakenError() error {
+	return &ValidationError{Fields: []FieldError{{Field: "name", Code: "duplicate", Message: "já existe uma etiqueta com esse nome no quadro"}}}
+}
+
+// etiquetas do board em ordem alfabética
+func (app *App) loadBoardLabels(ctx context.Context, boardID int) ([]Label, error) {
+	rows, err := app.db.Query(ctx, `SELECT `+labelSelectColumns+` FROM labels WHERE board_id = $1 ORDER BY lower(name)`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	labels := make([]Label, 0)
+	for rows.Next() {
+		var l Label
+		if err := scanLabel(rows, &l); err != nil {
+			return nil, err
+		}
+		labels = append(labels, l)
+	}
+	return labels, rows.Err()
+}
+
+// etiquetas de vários cards numa consulta só
+func (app *App) loadCardLabels(ctx context.Context, q cardQuerier, cardIDs []int) (map[int][]Label, error) {
+	byCard := make(map[int][]Label)
+	if len(cardIDs) == 0 {
+		return byCard, nil
+	}
+	rows, err := q.Query(ctx, `
+		SELECT cl.card_id, l.id, l.board_id, l.name, l.color, l.created_at
+		FROM card_labels cl JOIN labels l ON l.id = cl.label_id
+		WHERE cl.card_id = ANY($1)
+		ORDER BY lower(l.name)`, cardIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cardID int
+		var l Label
+		if err := rows.Scan(&cardID, &l.ID, &l.BoardID, &l.Name, &l.Color, &l.CreatedAt); err != nil {
+			return nil, err
+		}
+		byCard[cardID] = append(byCard[cardID], l)
+	}
+	return byCard, rows.Err()
+}
+
+// preencher Labels dos cards; card sem etiqueta recebe lista vazia
+func (app *App) attachCardLabels(ctx context.Context, cards []*Card) error {
+	ids := make([]int, len(cards))
+	for i, card := range cards {
+		ids[i] = card.ID
+	}
+	byCard, err := app.loadCardLabels(ctx, app.db, ids)
+	if err != nil {
+		return err
+	}
+	for _, card := range cards {
+		card.Labels = byCard[card.ID]
+		if card.Labels == nil {
+			card.Labels = []Label{}
+		}
+	}
+	return nil
+}
+
+// ?labels=1,2 e label_match=all|any
+func parseLabelFilter(c *fiber.Ctx) ([]int, bool, error) {
+	raw := c.Query("labels")
+	matchAll := c.Query("label_match", "any") == "all"
+	if raw == "" {
+		return nil, matchAll, nil
+	}
+	var ids []int
+	for _, part := range strings.Split(raw, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, matchAll, &ValidationError{Fields: []FieldError{{Field: "labels", Code: "invalid_id", Message: "lista de etiquetas inválida"}}}
+		}
+		ids = append(ids, id)
+	}
+	return uniqueInts(ids), matchAll, nil
+}
+
+// endpoint listar etiquetas do board
+func (app *App) getBoardLabels(c *fiber.Ctx) error {
+	labels, err := app.loadBoardLabels(context.Background(), authorizedBoardID(c))
+	if err != nil {
+		return internalError(c, "Erro ao buscar etiquetas")
+	}
+	return c.JSON(labels)
+}
+
+// endpoint criar etiqueta
+func (app *App) createLabel(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+	var label Label
+	if err := c.BodyParser(&label); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateLabel(&label); err != nil {
+		return validationFailed(c, err)
+	}
+	err := scanLabel(app.db.QueryRow(context.Background(),
+		`INSERT INTO labels (board_id, name, color) VALUES ($1, $2, $3) RETURNING `+labelSelectColumns,
+		boardID, label.Name, label.Color), &label)
+	if err != nil {
+		if labelNameTaken(err) {
+			return validationFailed(c, labelNameTakenError())
+		}
+		return internalError(c, "Erro ao criar etiqueta")
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_CREATED", SenderID: userID, Payload: label})
+	return c.Status(201).JSON(label)
+}
+
+// endpoint atualizar etiqueta
+func (app *App) updateLabel(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return badRequest(c, "ID da etiqueta inválido")
+	}
+	var label Label
+	if err := c.BodyParser(&label); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateLabel(&label); err != nil {
+		return validationFailed(c, err)
+	}
+	err = scanLabel(app.db.QueryRow(context.Background(),
+		`UPDATE labels SET name = $3, color = $4 WHERE id = $1 AND board_id = $2 RETURNING `+labelSelectColumns,
+		labelID, boardID, label.Name, label.Color), &label)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Etiqueta não encontrada")
+		}
+		if labelNameTaken(err) {
+			return validationFailed(c, labelNameTakenError())
+		}
+		return internalError(c, "Erro ao atualizar etiqueta")
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_UPDATED", SenderID: userID, Payload: label})
+	return c.JSON(label)
+}
+
+// endpoint excluir etiqueta; sai de todos os cards do board
+func (app *App) deleteLabel(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return badRequest(c, "ID da etiqueta inválido")
+	}
+	tag, err := app.db.Exec(context.Background(), "DELETE FROM labels WHERE id = $1 AND board_id = $2", labelID, boardID)
+	if err != nil {
+		return internalError(c, "Erro ao excluir etiqueta")
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound(c, "Etiqueta não encontrada")
+	}
+	app.broadcast(boardID, WsMessage{Type: "LABEL_DELETED", SenderID: userID, Payload: fiber.Map{"label_id": labelID}})
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// endpoint definir as etiquetas do card (substitui o conjunto)
+func (app *App) setCardLabels(c *fiber.Ctx) error {
+	var payload struct {
+		LabelIDs []int `json:"label_ids"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	return app.changeCardLabels(c, func([]int) []int { return uniqueInts(payload.LabelIDs) })
+}
+
+// endpoint adicionar etiqueta ao card
+func (app *App) addCardLabel(c *fiber.Ctx) error {
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return badRequest(c, "ID da etiqueta inválido")
+	}
+	return app.changeCardLabels(c, func(current []int) []int { return uniqueInts(append(current, labelID)) })
+}
+
+// endpoint remover etiqueta do card
+func (app *App) removeCardLabel(c *fiber.Ctx) error {
+	labelID, err := strconv.Atoi(c.Params("labelId"))
+	if err != nil {
+		return badRequest(c, "ID da etiqueta inválido")
+	}
+	return app.changeCardLabels(c, func(current []int) []int {
+		next := make([]int, 0, len(current))
+		for _, id := range current {
+			if id != labelID {
+				next = append(next, id)
+			}
+		}
+		return next
+	})
+}
+
+// aplicar a mudança no conjunto de etiquetas, registrar histórico e avisar o board
+func (app *App) changeCardLabels(c *fiber.Ctx, change func(current []int) []int) error {
+	cardID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	// trava o card para mudanças concorrentes nas etiquetas
+	if _, err := tx.Exec(ctx, "SELECT 1 FROM cards WHERE id = $1 FOR UPDATE", cardID); err != nil {
+		return internalError(c, "Erro ao buscar o card")
+	}
+	before, err := app.loadCardLabels(ctx, tx, []int{cardID})
+	if err != nil {
+		return internalError(c, "Erro ao buscar etiquetas do card")
+	}
+	current := labelIDs(before[cardID])
+	next := nonNilInts(change(current))
+
+	if len(next) > 0 {
+		var found int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM labels WHERE id = ANY($1) AND board_id = $2", next, boardID).Scan(&found); err != nil {
+			return internalError(c, "Erro ao validar etiquetas")
+		}
+		if found != len(next) {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "label_ids", Code: "not_found", Message: "etiqueta não encontrada neste quadro"}}})
+		}
+	}
+	if _, err := tx.Exec(ctx, "DELETE FROM card_labels WHERE card_id = $1 AND NOT (label_id = ANY($2))", cardID, next); err != nil {
+		return internalError(c, "Erro ao atualizar etiquetas")
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO card_labels (card_id, label_id)
+		SELECT $1, unnest($2::int[]) ON CONFLICT DO NOTHING`, cardID, next); err != nil {
+		return internalError(c, "Erro ao atualizar etiquetas")
+	}
+
+	after, err := app.loadCardLabels(ctx, tx, []int{cardID})
+	if err != nil {
+		return internalError(c, "Erro ao buscar etiquetas do card")
+	}
+	labels := after[cardID]
+	if labels == nil {
+		labels = []Label{}
+	}
+	if !sameInts(current, labelIDs(labels)) {
+		if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventFieldChanged, "labels", labelNames(before[cardID]), labelNames(labels)); err != nil {
+			log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+			return internalError(c, "Erro ao registrar histórico")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar etiquetas")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "CARD_LABELS_UPDATED", SenderID: userID, Payload: fiber.Map{"card_id": cardID, "labels": labels}})
+	return c.JSON(labels)
+}
+
+// card movido para outro board: etiquetas do board antigo não valem no novo
+func (app *App) dropForeignCardLabels(ctx context.Context, tx pgx.Tx, cardID, boardID int, actorID string) error {
+	before, err := app.loadCardLabels(ctx, tx, []int{cardID})
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx, `DELETE FROM card_labels cl USING labels l
+		WHERE cl.card_id = $1 AND l.id = cl.label_id AND l.board_id <> $2`, cardID, boardID)
+	if err != nil || tag.RowsAffected() == 0 {
+		return err
+	}
+	after, err := app.loadCardLabels(ctx, tx, []int{cardID})
+	if err != nil {
+		return err
+	}
+	return app.recordCardEvent(tx, cardID, boardID, actorID, CardEventFieldChanged, "labels", labelNames(before[cardID]), labelNames(after[cardID]))
+}
+
+func labelIDs(labels []Label) []int {
+	ids := make([]int, len(labels))
+	for i, l := range labels {
+		ids[i] = l.ID
+	}
+	return ids
+}
+
+func labelNames(labels []Label) []string {
+	names := make([]string, len(labels))
+	for i, l := range labels {
+		names[i] = l.Name
+	}
+	return names
+}
+
+func sameInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a, b = append([]int(nil), a...), append([]int(nil), b...)
+	sort.Ints(a)
+	sort.Ints(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -12,10 +12,12 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -52,6 +54,9 @@ type Board struct {
 	Color       string    `json:"color" db:"color"`
 	IsPublic    bool      `json:"is_public" db:"is_public"`
 	OwnerName   string    `json:"owner_name,omitempty" db:"owner_name"`
+	Team        *string   `json:"team,omitempty" db:"team"`
+	IsDefault   bool      `json:"is_default" db:"is_default"`
+	Version     int       `json:"version" db:"version"`
 }
 
 // estrutura column
@@ -61,21 +66,28 @@ type Column struct {
 	Title    string `json:"title" db:"title"`
 	Position int    `json:"position" db:"position"`
 	Color    string `json:"color" db:"color"`
+	Version  int    `json:"version" db:"version"`
 }
 
 // estrutura card
 type Card struct {
-	ID          int        `json:"id" db:"id"`
-	ColumnID    int        `json:"column_id" db:"column_id"`
-	Title       string     `json:"title" db:"title"`
-	Description string     `json:"description" db:"description"`
-	AssignedTo  string     `json:"assigned_to" db:"assigned_to"`
-	Priority    string     `json:"priority" db:"priority"`
-	DueDate     *time.Time `json:"due_date" db:"due_date"`
-	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
-	Position    int        `json:"position" db:"position"`
-	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	ID          int           `json:"id" db:"id"`
+	ColumnID    int           `json:"column_id" db:"column_id"`
+	Title       string        `json:"title" db:"title"`
+	Description string        `json:"description" db:"description"`
+	AssignedTo  string        `json:"assigned_to" db:"assigned_to"`
+	Priority    string        `json:"priority" db:"priority"`
+	DueDate     *time.Time    `json:"due_date" db:"due_date"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at" db:"updated_at"`
+	Position    int           `json:"position" db:"position"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty" db:"completed_at"`
+	ArchivedAt  *time.Time    `json:"archived_at,omitempty" db:"archived_at"`
+	Version     int           `json:"version" db:"version"`
+	Labels      []Label       `json:"labels,omitempty" db:"-"`
+	Assignees   []UserSummary `json:"assignees,omitempty" db:"-"`
+	// só na entrada: substitui os responsáveis; assigned_to passa a ser derivado deles
+	AssigneeIDs []string `json:"assignee_ids,omitempty" db:"-"`
 }
 
 // estrutura notification
@@ -105,6 +117,7 @@ type BoardInvitation struct {
 
 // estrutura wsmessage
 type WsMessage struct {
+	Seq      int64       `json:"seq,omitempty"`
 	SenderID string      `json:"sender_id,omitempty"`
 	Type     string      `json:"type"`
 	Payload  interface{} `json:"payload"`
@@ -123,6 +136,7 @@ type Ligacao struct {
 	Observations   *string    `json:"observations,omitempty" db:"observations"`
 	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	Version        int        `json:"version" db:"version"`
 }
 
 // estrutura ligacoes
@@ -135,6 +149,7 @@ type AgendaEvent struct {
 	UserID      *string   `json:"user_id,omitempty" db:"user_id"`
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Version     int       `json:"version" db:"version"`
 }
 
 // estrutura avaliacoes
@@ -151,6 +166,7 @@ type Avaliacao struct {
 	ResolutionNotes *string   `json:"resolution_notes,omitempty" db:"resolution_notes"`
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	Version         int       `json:"version" db:"version"`
 }
 
 // estrutura contatos
@@ -174,9 +190,18 @@ type ReorderPayload struct {
 
 // estrutura App
 type App struct {
-	db       *pgxpool.Pool
-	clients  map[int]map[*websocket.Conn]bool
-	colLocks struct {
+	db         *pgxpool.Pool
+	hub        *Hub
+	presence   *PresenceRegistry
+	bus        EventBus
+	instanceID string
+	access     accessLookup
+	colLocks   struct {
+		mu    sync.Mutex
+		locks map[int]*sync.Mutex
+	}
+	// serializa numeração e envio dos eventos de cada board
+	eventLocks struct {
 		mu    sync.Mutex
 		locks map[int]*sync.Mutex
 	}
@@ -227,6 +252,12 @@ func (app *App) getDisplayName(ctx context.Context, tx pgx.Tx, userID string) st
 	return email
 }
 
+// subprotocolo usado para enviar o token no handshake
+const wsAuthSubprotocol = "bearer"
+
+// código de fechamento quando o acesso ao board é removido
+const wsCloseAccessRevoked = 4403
+
 // claims Supabase JWT
 type SupabaseClaims struct {
 	UserID string `json:"sub"`
@@ -255,16 +286,25 @@ func (app *App) connectDB() error {
 func (app *App) authMiddleware(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
 	if authHeader == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Cabeçalho de autorização ausente"})
+		return unauthorized(c, "Cabeçalho de autorização ausente")
 	}
 	parts := strings.Split(authHeader, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Formato de autorização inválido. Esperado: Bearer <token>"})
+		return unauthorized(c, "Formato de autorização inválido. Esperado: Bearer <token>")
 	}
-	tokenString := parts[1]
+	userID, status, msg := validateSupabaseToken(parts[1])
+	if userID == "" {
+		return authFailed(c, status, msg)
+	}
+	c.Locals("userID", userID)
+	return c.Next()
+}
+
+// validar jwt do supabase, retorna status e mensagem quando inválido
+func validateSupabaseToken(tokenString string) (string, int, string) {
 	jwtSecret := os.Getenv("SUPABASE_JWT_SECRET")
 	if jwtSecret == "" {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Configuração do servidor incorreta"})
+		return "", fiber.StatusInternalServerError, "Configuração do servidor incorreta"
 	}
 	token, err := jwt.ParseWithClaims(tokenString, &SupabaseClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -274,57 +314,138 @@ func (app *App) authMiddleware(c *fiber.Ctx) error {
 	}, jwt.WithAudience("authenticated"))
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token expirado"})
+			return "", fiber.StatusUnauthorized, "Token expirado"
 		}
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token inválido"})
+		return "", fiber.StatusUnauthorized, "Token inválido"
 	}
 	if !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Token inválido ou expirado"})
+		return "", fiber.StatusUnauthorized, "Token inválido ou expirado"
 	}
 	claims, ok := token.Claims.(*SupabaseClaims)
 	if !ok || claims.UserID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Claims do token inválidas ou ID de usuário ausente"})
+		return "", fiber.StatusUnauthorized, "Claims do token inválidas ou ID de usuário ausente"
 	}
-	c.Locals("userID", claims.UserID)
-	return c.Next()
+	return claims.UserID, 0, ""
 }
 
-// websocket
-func (app *App) handleWebSocket(c *websocket.Conn) {
+// middleware auth do websocket: token via ?token= ou Sec-WebSocket-Protocol
+func (app *App) wsAuthMiddleware(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
 	boardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		c.Close()
-		return
+		return badRequest(c, "ID de board inválido")
 	}
-	if app.clients[boardID] == nil {
-		app.clients[boardID] = make(map[*websocket.Conn]bool)
-	}
-	app.clients[boardID][c] = true
-	defer func() {
-		delete(app.clients[boardID], c)
-		if len(app.clients[boardID]) == 0 {
-			delete(app.clients, boardID)
+
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		// o navegador envia "bearer, <token>" como subprotocolos
+		for _, proto := range strings.Split(c.Get("Sec-WebSocket-Protocol"), ",") {
+			proto = strings.TrimSpace(proto)
+			if proto != "" && proto != wsAuthSubprotocol {
+				tokenString = proto
+			}
 		}
+	}
+	if tokenString == "" {
+		return unauthorized(c, "Token ausente")
+	}
+	userID, status, msg := validateSupabaseToken(tokenString)
+	if userID == "" {
+		return authFailed(c, status, msg)
+	}
+
+	hasPermission, err := app.checkBoardPermission(userID, boardID)
+	if err != nil || !hasPermission {
+		return forbidden(c)
+	}
+	c.Locals("userID", userID)
+	c.Locals("boardID", boardID)
+	return c.Next()
+}
+
+// websocket
+func (app *App) handleWebSocket(c *websocket.Conn) {
+	boardID, _ := c.Locals("boardID").(int)
+	userID, _ := c.Locals("userID").(string)
+	if boardID == 0 || userID == "" {
 		c.Close()
-	}()
-	for {
-		if _, _, err := c.ReadMessage(); err != nil {
-			break
-		}
+		return
 	}
+	user := app.getUserSummary(userID)
+	app.hub.Serve(c, boardID, userID, wsHandlers{
+		OnRegister: func(client *wsClient) error {
+			app.presenceJoin(client, user)
+			return app.replayBoardEvents(client, c.Query("since"))
+		},
+		OnMessage: app.handleClientMessage,
+		OnClose:   app.presenceLeave,
+	})
 }
 
 // broadcast
 func (app *App) broadcast(boardID int, message WsMessage) {
-	if clients, ok := app.clients[boardID]; ok {
-		payloadBytes, _ := json.Marshal(message)
-		for client := range clients {
-			if err := client.WriteMessage(websocket.TextMessage, payloadBytes); err != nil {
-				client.Close()
-				delete(clients, client)
-			}
-		}
+	app.emitBoardEvent(boardID, message, app.appendBoardEvent)
+}
+
+// numerar, entregar e publicar sob o lock do board, para que nesta instância os
+// eventos de um board sejam enfileirados na ordem de seq
+func (app *App) emitBoardEvent(boardID int, message WsMessage, appendEvent func(int, WsMessage) (int64, error)) {
+	lock := app.boardEventLock(boardID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	seq, err := appendEvent(boardID, message)
+	if err != nil {
+		log.Printf("Erro ao registrar evento %s do board %d: %v", message.Type, boardID, err)
 	}
+	message.Seq = seq
+	payloadBytes, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Erro ao serializar mensagem %s: %v", message.Type, err)
+		return
+	}
+	app.hub.Broadcast(boardID, seq, payloadBytes)
+	app.publishBoardEvent(BoardEvent{Kind: BoardEventMessage, BoardID: boardID, Seq: seq, Message: payloadBytes})
+}
+
+func (app *App) boardEventLock(boardID int) *sync.Mutex {
+	app.eventLocks.mu.Lock()
+	defer app.eventLocks.mu.Unlock()
+	if app.eventLocks.locks == nil {
+		app.eventLocks.locks = make(map[int]*sync.Mutex)
+	}
+	lock, ok := app.eventLocks.locks[boardID]
+	if !ok {
+		lock = &sync.Mutex{}
+		app.eventLocks.locks[boardID] = lock
+	}
+	return lock
+}
+
+// fechar sockets de um board; userID vazio fecha todos
+func (app *App) disconnectBoardClients(boardID int, userID string) {
+	app.hub.Disconnect(boardID, userID, wsCloseAccessRevoked, "acesso revogado")
+	app.publishBoardEvent(BoardEvent{
+		Kind:    BoardEventDisconnect,
+		BoardID: boardID,
+		UserID:  userID,
+		Code:    wsCloseAccessRevoked,
+		Reason:  "acesso revogado",
+	})
+}
+
+// fechar os sockets de todos menos keepUserIDs (dono e membros de um board que virou privado)
+func (app *App) disconnectBoardClientsExcept(boardID int, keepUserIDs []string) {
+	app.hub.DisconnectExcept(boardID, keepUserIDs, wsCloseAccessRevoked, "acesso revogado")
+	app.publishBoardEvent(BoardEvent{
+		Kind:        BoardEventDisconnect,
+		BoardID:     boardID,
+		KeepUserIDs: keepUserIDs,
+		Code:        wsCloseAccessRevoked,
+		Reason:      "acesso revogado",
+	})
 }
 
 // avatar users
@@ -332,20 +453,20 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 	file, err := c.FormFile("avatar")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nenhum arquivo de avatar enviado"})
+		return badRequest(c, "Nenhum arquivo de avatar enviado")
 	}
 	contentType := file.Header.Get("Content-Type")
 	if !strings.HasPrefix(contentType, "image/") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Formato de arquivo inválido. Apenas imagens são permitidas."})
+		return badRequest(c, "Formato de arquivo inválido. Apenas imagens são permitidas.")
 	}
 	src, err := file.Open()
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao abrir o arquivo"})
+		return internalError(c, "Erro ao abrir o arquivo")
 	}
 	defer src.Close()
 	fileBytes, err := io.ReadAll(src)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao ler o arquivo"})
+		return internalError(c, "Erro ao ler o arquivo")
 	}
 	ext := filepath.Ext(file.Filename)
 	fileName := fmt.Sprintf("avatar-%s%s", userID, ext)
@@ -355,7 +476,7 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 	req, err := http.NewRequest("POST", uploadURL, bytes.NewReader(fileBytes))
 	if err != nil {
 		log.Printf("❌ Erro ao criar requisição para o Supabase: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao preparar upload"})
+		return internalError(c, "Erro interno ao preparar upload")
 	}
 	req.Header.Set("Authorization", "Bearer "+supabaseKey)
 	req.Header.Set("Content-Type", contentType)
@@ -364,13 +485,13 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("❌ Erro ao fazer upload para o Supabase: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao fazer upload"})
+		return internalError(c, "Erro interno ao fazer upload")
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		log.Printf("❌ Supabase retornou status não-OK: %s, Body: %s", resp.Status, string(body))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Falha ao armazenar o arquivo"})
+		return internalError(c, "Falha ao armazenar o arquivo")
 	}
 	publicURL := fmt.Sprintf("%s/storage/v1/object/public/avatars/%s", supabaseURL, fileName)
 	query := `
@@ -381,7 +502,7 @@ func (app *App) handleAvatarUpload(c *fiber.Ctx) error {
 	_, err = app.db.Exec(context.Background(), query, publicURL, userID)
 	if err != nil {
 		log.Printf("❌ Erro ao atualizar o avatar do usuário no DB: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar perfil"})
+		return internalError(c, "Erro ao atualizar perfil")
 	}
 	return c.JSON(fiber.Map{"avatar_url": publicURL})
 }
@@ -391,7 +512,8 @@ func (app *App) getBoardIDFromCard(cardID int) (int, error) {
 	var boardID int
 	query := `SELECT c.board_id FROM columns c
 			  INNER JOIN cards ca ON c.id = ca.column_id
-			  WHERE ca.id = $1`
+			  INNER JOIN boards b ON b.id = c.board_id
+			  WHERE ca.id = $1 AND ca.deleted_at IS NULL AND c.deleted_at IS NULL AND b.deleted_at IS NULL`
 	err := app.db.QueryRow(context.Background(), query, cardID).Scan(&boardID)
 	if err != nil {
 		return 0, err
@@ -403,14 +525,27 @@ func (app *App) getBoardIDFromCard(cardID int) (int, error) {
 func (app *App) createColumn(c *fiber.Ctx) error {
 	var col Column
 	if err := c.BodyParser(&col); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "dados de coluna inválidos"})
+		return invalidBody(c)
 	}
 	if col.BoardID == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "board_id é obrigatório"})
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "board_id", Code: "required", Message: "campo obrigatório"}}})
+	}
+	if err := validateColumn(&col); err != nil {
+		return validationFailed(c, err)
+	}
+	if !app.requireBoardRole(c, col.BoardID, RoleEditor) {
+		return nil
+	}
+	if isSystemColumnTitle(col.Title) {
+		var isPublic bool
+		app.db.QueryRow(context.Background(), "SELECT is_public FROM boards WHERE id = $1", col.BoardID).Scan(&isPublic)
+		if isPublic {
+			return validationFailed(c, reservedColumnTitle())
+		}
 	}
 	var maxPos sql.NullInt64
 	err := app.db.QueryRow(context.Background(),
-		"SELECT MAX(position) FROM columns WHERE board_id = $1", col.BoardID).Scan(&maxPos)
+		"SELECT MAX(position) FROM columns WHERE board_id = $1 AND deleted_at IS NULL", col.BoardID).Scan(&maxPos)
 	if err != nil {
 		maxPos.Int64 = -1
 	}
@@ -418,12 +553,12 @@ func (app *App) createColumn(c *fiber.Ctx) error {
 	query := `
         INSERT INTO columns (board_id, title, position, color)
         VALUES ($1, $2, $3, $4)
-        RETURNING id
+        RETURNING id, version
     `
 	err = app.db.QueryRow(context.Background(), query,
-		col.BoardID, col.Title, col.Position, col.Color).Scan(&col.ID)
+		col.BoardID, col.Title, col.Position, col.Color).Scan(&col.ID, &col.Version)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "erro ao criar coluna"})
+		return internalError(c, "erro ao criar coluna")
 	}
 	app.broadcast(col.BoardID, WsMessage{Type: "COLUMN_CREATED", Payload: col})
 	return c.Status(201).JSON(col)
@@ -433,33 +568,59 @@ func (app *App) createColumn(c *fiber.Ctx) error {
 func (app *App) updateColumn(c *fiber.Ctx) error {
 	columnID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
+		return badRequest(c, "ID da coluna inválido")
 	}
 
 	var col Column
 	if err := c.BodyParser(&col); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Dados de coluna inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateColumn(&col); err != nil {
+		return validationFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, col.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
+	}
+
+	var currentTitle string
+	var isPublic bool
+	err = app.db.QueryRow(context.Background(), `
+		SELECT col.title, b.is_public FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.id = $1`, columnID).Scan(&currentTitle, &isPublic)
+	if err != nil {
+		return notFound(c, "Coluna não encontrada")
+	}
+	if isPublic && !strings.EqualFold(strings.TrimSpace(col.Title), strings.TrimSpace(currentTitle)) {
+		if isSystemColumnTitle(currentTitle) {
+			return systemColumnProtected(c)
+		}
+		if isSystemColumnTitle(col.Title) {
+			return validationFailed(c, reservedColumnTitle())
+		}
 	}
 
 	query := `
 		UPDATE columns 
-		SET title = $1, color = $2 
-		WHERE id = $3 
-		RETURNING id, board_id, title, position, color
-	`
-	err = app.db.QueryRow(context.Background(), query, col.Title, col.Color, columnID).Scan(
-		&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color,
-	)
+		SET title = $1, color = $2, version = version + 1
+		WHERE id = $3 AND ($4 = 0 OR version = $4)
+		RETURNING ` + columnSelectColumns
+	err = scanColumn(app.db.QueryRow(context.Background(), query, col.Title, col.Color, columnID, expectedVersion), &col)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Coluna não encontrada"})
+			return conditionalUpdateMiss(c, func() (interface{}, int, error) {
+				var current Column
+				err := scanColumn(app.db.QueryRow(context.Background(), `SELECT `+columnSelectColumns+` FROM columns WHERE id = $1`, columnID), &current)
+				return current, current.Version, err
+			}, "Coluna não encontrada")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar coluna"})
+		return internalError(c, "Erro ao atualizar coluna")
 	}
 
 	app.broadcast(col.BoardID, WsMessage{Type: "COLUMN_UPDATED", Payload: col})
 
+	setVersionETag(c, col.Version)
 	return c.Status(200).JSON(col)
 }
 
@@ -467,36 +628,74 @@ func (app *App) updateColumn(c *fiber.Ctx) error {
 func (app *App) deleteColumn(c *fiber.Ctx) error {
 	columnID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID da coluna inválido"})
+		return badRequest(c, "ID da coluna inválido")
+	}
+	isSystem, err := app.isSystemColumn(columnID)
+	if err != nil {
+		return notFound(c, "Coluna não encontrada")
+	}
+	if isSystem {
+		return systemColumnProtected(c)
 	}
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro interno do servidor"})
+		return internalError(c, "Erro interno do servidor")
 	}
 	defer tx.Rollback(context.Background())
+	// só os cards visíveis impedem a exclusão; os arquivados vão junto para a lixeira
 	var cardCount int
-	err = tx.QueryRow(context.Background(), "SELECT COUNT(*) FROM cards WHERE column_id = $1", columnID).Scan(&cardCount)
+	err = tx.QueryRow(context.Background(), "SELECT COUNT(*) FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL", columnID).Scan(&cardCount)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao verificar cards na coluna"})
+		return internalError(c, "Erro ao verificar cards na coluna")
 	}
 	if cardCount > 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "A coluna não pode ser excluída pois contém tarefas."})
+		return badRequest(c, "A coluna não pode ser excluída pois contém tarefas.")
 	}
 	var boardID, position int
 	err = tx.QueryRow(context.Background(), "SELECT board_id, position FROM columns WHERE id = $1", columnID).Scan(&boardID, &position)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
+		return notFound(c, "Coluna não encontrada")
 	}
-	_, err = tx.Exec(context.Background(), "DELETE FROM columns WHERE id = $1", columnID)
+	// vai para a lixeira; o purge remove de vez após o período de retenção
+	userID := c.Locals("userID").(string)
+	_, err = tx.Exec(context.Background(), "UPDATE columns SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1",
+		columnID, userID)
+	if err != nil {
+		return internalError(c, "Erro ao deletar a coluna")
+	}
+	// arquivados recebem o mesmo deleted_at da coluna, para voltarem com ela
+	rows, err := tx.Query(context.Background(), `UPDATE cards ca SET deleted_at = col.deleted_at, deleted_by = $2
+		FROM columns col WHERE col.id = $1 AND ca.column_id = col.id AND ca.deleted_at IS NULL
+		RETURNING ca.id, ca.title`, columnID, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar a coluna"})
+		return internalError(c, "Erro ao deletar os cards arquivados")
+	}
+	archived := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			rows.Close()
+			return internalError(c, "Erro ao deletar os cards arquivados")
+		}
+		archived[id] = title
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return internalError(c, "Erro ao deletar os cards arquivados")
+	}
+	for id, title := range archived {
+		if err := app.recordCardEvent(tx, id, boardID, userID, CardEventDeleted, "", fiber.Map{"title": title}, nil); err != nil {
+			log.Printf("Erro ao registrar histórico do card %d: %v", id, err)
+			return internalError(c, "Erro ao registrar histórico")
+		}
 	}
-	_, err = tx.Exec(context.Background(), "UPDATE columns SET position = position - 1 WHERE board_id = $1 AND position > $2", boardID, position)
+	_, err = tx.Exec(context.Background(), "UPDATE columns SET position = position - 1 WHERE board_id = $1 AND position > $2 AND deleted_at IS NULL", boardID, position)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar colunas"})
+		return internalError(c, "Erro ao reordenar colunas")
 	}
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a exclusão"})
+		return internalError(c, "Erro ao confirmar a exclusão")
 	}
 	app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", Payload: nil})
 	return c.Status(200).JSON(fiber.Map{"status": "deleted"})
@@ -506,24 +705,27 @@ func (app *App) deleteColumn(c *fiber.Ctx) error {
 func (app *App) deleteBoard(c *fiber.Ctx) error {
 	boardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
+		return badRequest(c, "ID de board inválido")
 	}
 	userID := c.Locals("userID").(string)
 	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
+	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1 AND deleted_at IS NULL", boardID).Scan(&ownerID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
+			return notFound(c, "Quadro não encontrado")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar o quadro"})
+		return internalError(c, "Erro ao verificar o quadro")
 	}
 	if ownerID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado. Você não é o dono deste quadro."})
+		return accessDenied(c, "Acesso negado. Você não é o dono deste quadro.")
 	}
-	_, err = app.db.Exec(context.Background(), "DELETE FROM boards WHERE id = $1", boardID)
+	// vai para a lixeira e deixa de ser o padrão do time
+	_, err = app.db.Exec(context.Background(),
+		"UPDATE boards SET deleted_at = NOW(), deleted_by = $2, is_default = false WHERE id = $1", boardID, userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao deletar o quadro"})
+		return internalError(c, "Erro ao deletar o quadro")
 	}
+	app.disconnectBoardClients(boardID, "")
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -532,7 +734,7 @@ func (app *App) handleGetContatosStatus(c *fiber.Ctx) error {
 	rows, err := app.db.Query(context.Background(), query)
 	if err != nil {
 		log.Printf("Erro ao buscar status de contatos: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar dados de status"})
+		return internalError(c, "Erro ao buscar dados de status")
 	}
 	defer rows.Close()
 
@@ -567,11 +769,15 @@ func (app *App) handleSetContatoStatus(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
-	if payload.ContatoID == "" || payload.Status == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "contato_id e status são obrigatórios"})
+	var v Validator
+	v.Required("contato_id", payload.ContatoID, maxShortTextLength)
+	v.OneOf("status", payload.Status, contatoStatuses)
+	v.MaxLength("anotacao", payload.Anotacao, maxLongTextLength)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
 	}
 
 	query := `
@@ -590,7 +796,7 @@ func (app *App) handleSetContatoStatus(c *fiber.Ctx) error {
 	err := app.db.QueryRow(context.Background(), query, payload.ContatoID, payload.Status, payload.Anotacao, userID).Scan(&returnedId)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert do status do contato: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar o status no banco de dados"})
+		return internalError(c, "Erro ao salvar o status no banco de dados")
 	}
 
 	return c.Status(200).JSON(fiber.Map{"status": "success", "id": returnedId})
@@ -613,11 +819,11 @@ func (app *App) handleAssignContato(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
 	if payload.ContatoID == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "contato_id é obrigatório"})
+		return badRequest(c, "contato_id é obrigatório")
 	}
 
 	query := `
@@ -641,7 +847,7 @@ func (app *App) handleAssignContato(c *fiber.Ctx) error {
 	err = app.db.QueryRow(context.Background(), query, payload.ContatoID, userID, userID, userName.String, userAvatar.String).Scan(&returnedId, &status, &anotacao, &updatedAt)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert para assumir contato: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao salvar a atribuição no banco de dados"})
+		return internalError(c, "Erro ao salvar a atribuição no banco de dados")
 	}
 
 	return c.Status(200).JSON(fiber.Map{
@@ -661,11 +867,11 @@ func (app *App) handleUnassignContato(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
 	if payload.ContatoID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "contato_id é obrigatório"})
+		return badRequest(c, "contato_id é obrigatório")
 	}
 
 	var isAdmin bool
@@ -703,11 +909,11 @@ func (app *App) handleUnassignContato(c *fiber.Ctx) error {
 
 	if execErr != nil {
 		log.Printf("Erro ao desassociar contato (admin=%t): %v", isAdmin, execErr)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao remover associação no banco de dados"})
+		return internalError(c, "Erro ao remover associação no banco de dados")
 	}
 
 	if cmdTag.RowsAffected() == 0 {
-		return c.Status(403).JSON(fiber.Map{"error": "Tarefa não encontrada ou você não tem permissão para desassociá-la."})
+		return accessDenied(c, "Tarefa não encontrada ou você não tem permissão para desassociá-la.")
 	}
 
 	return c.Status(200).JSON(fiber.Map{"status": "success"})
@@ -725,27 +931,75 @@ func (app *App) setupRoutes(fiberApp *fiber.App) {
 
 	// Rotas do Kanban
 	protected.Get("/boards/public", app.getPublicBoards)
+	protected.Get("/boards/public/default", app.getDefaultPublicBoard)
 	protected.Get("/boards/private", app.getPrivateBoards)
 	protected.Post("/boards", app.createBoard)
+	protected.Get("/templates", app.getBoardTemplates)
+	protected.Get("/trash", app.getTrash)
+	protected.Post("/trash/boards/:id/restore", app.restoreBoard)
+	protected.Post("/trash/columns/:id/restore", app.restoreColumn)
+	protected.Post("/trash/cards/:id/restore", app.restoreCard)
+	protected.Delete("/templates/:id", app.deleteBoardTemplate)
+	protected.Get("/views", app.getViews)
+	protected.Post("/views", app.createView)
+	protected.Get("/views/:id", app.getView)
+	protected.Put("/views/:id", app.updateView)
+	protected.Delete("/views/:id", app.deleteView)
+	protected.Get("/views/:id/cards", app.getViewCards)
+	protected.Get("/views/:id/board", app.getViewBoard)
+	protected.Get("/boards/:id", app.requireAccess(resourceBoard, "id", RoleViewer), app.getBoard)
+	protected.Get("/boards/:id/full", app.requireAccess(resourceBoard, "id", RoleViewer), app.getBoardFull)
+	protected.Post("/boards/:id/clone", app.requireAccess(resourceBoard, "id", RoleEditor), app.cloneBoard)
+	protected.Post("/boards/:id/save-as-template", app.requireAccess(resourceBoard, "id", RoleOwner), app.saveBoardAsTemplate)
+	protected.Put("/boards/:id", app.requireAccess(resourceBoard, "id", RoleOwner), app.updateBoard)
+	protected.Post("/boards/:id/default", app.requireAccess(resourceBoard, "id", RoleOwner), app.setDefaultBoard)
+	protected.Patch("/boards/:id", app.requireAccess(resourceBoard, "id", RoleOwner), app.patchBoard)
 	protected.Delete("/boards/:id", app.deleteBoard)
-	protected.Get("/boards/:id/columns", app.getColumns)
-	protected.Post("/boards/:id/columns/reorder", app.reorderColumns)
+	protected.Get("/boards/:id/columns", app.requireAccess(resourceBoard, "id", RoleViewer), app.getColumns)
+	protected.Post("/boards/:id/columns/reorder", app.requireAccess(resourceBoard, "id", RoleEditor), app.reorderColumns)
 	protected.Post("/columns", app.createColumn)
-	protected.Put("/columns/:id", app.updateColumn)
-	protected.Delete("/columns/:id", app.deleteColumn)
-	protected.Get("/columns/:id/cards", app.getCards)
-	protected.Post("/columns/:id/cards", app.createCard)
-	protected.Put("/cards/:id", app.updateCard)
-	protected.Delete("/cards/:id", app.deleteCard)
-	protected.Post("/cards/move", app.moveCard)
+	protected.Put("/columns/:id", app.requireAccess(resourceColumn, "id", RoleEditor), app.updateColumn)
+	protected.Delete("/columns/:id", app.requireAccess(resourceColumn, "id", RoleEditor), app.deleteColumn)
+	protected.Get("/columns/:id/cards", app.requireAccess(resourceColumn, "id", RoleViewer), app.getCards)
+	protected.Post("/columns/:id/cards", app.requireAccess(resourceColumn, "id", RoleEditor), app.createCard)
+	protected.Put("/cards/:id", app.requireAccess(resourceCard, "id", RoleEditor), app.updateCard)
+	protected.Patch("/cards/:id", app.requireAccess(resourceCard, "id", RoleEditor), app.patchCard)
+	protected.Delete("/cards/:id", app.requireAccess(resourceCard, "id", RoleEditor), app.deleteCard)
+	protected.Post("/cards/:id/archive", app.requireAccess(resourceCard, "id", RoleEditor), app.archiveCard)
+	protected.Post("/cards/:id/unarchive", app.requireAccess(resourceCard, "id", RoleEditor), app.unarchiveCard)
+	protected.Post("/columns/:id/archive", app.requireAccess(resourceColumn, "id", RoleEditor), app.archiveColumnCards)
+	protected.Post("/cards/move", app.requireBodyAccess(resourceCard, "card_id", RoleEditor), app.moveCard)
+	protected.Post("/cards/bulk", app.bulkCards)
+	protected.Get("/cards/search", app.getCardSearch)
+	protected.Get("/cards/:id/history", app.getCardHistory)
+	protected.Get("/boards/:id/activity", app.requireAccess(resourceBoard, "id", RoleViewer), app.getBoardActivity)
+	protected.Get("/boards/:id/labels", app.requireAccess(resourceBoard, "id", RoleViewer), app.getBoardLabels)
+	protected.Post("/boards/:id/labels", app.requireAccess(resourceBoard, "id", RoleEditor), app.createLabel)
+	protected.Put("/boards/:id/labels/:labelId", app.requireAccess(resourceBoard, "id", RoleEditor), app.updateLabel)
+	protected.Delete("/boards/:id/labels/:labelId", app.requireAccess(resourceBoard, "id", RoleEditor), app.deleteLabel)
+	protected.Put("/cards/:id/labels", app.requireAccess(resourceCard, "id", RoleEditor), app.setCardLabels)
+	protected.Post("/cards/:id/labels/:labelId", app.requireAccess(resourceCard, "id", RoleEditor), app.addCardLabel)
+	protected.Delete("/cards/:id/labels/:labelId", app.requireAccess(resourceCard, "id", RoleEditor), app.removeCardLabel)
+	protected.Put("/cards/:id/assignees", app.requireAccess(resourceCard, "id", RoleEditor), app.setCardAssignees)
+	protected.Post("/cards/:id/assignees/:userId", app.requireAccess(resourceCard, "id", RoleEditor), app.addCardAssignee)
+	protected.Delete("/cards/:id/assignees/:userId", app.requireAccess(resourceCard, "id", RoleEditor), app.removeCardAssignee)
+	protected.Get("/boards/:id/presence", app.getBoardPresence)
+
+	// Comentários dos cards
+	protected.Get("/cards/:id/comments", app.requireAccess(resourceCard, "id", RoleViewer), app.getCardComments)
+	protected.Post("/cards/:id/comments", app.requireAccess(resourceCard, "id", RoleCommenter), app.createCardComment)
+	protected.Put("/cards/:id/comments/:commentId", app.requireAccess(resourceCard, "id", RoleCommenter), app.updateCardComment)
+	protected.Delete("/cards/:id/comments/:commentId", app.requireAccess(resourceCard, "id", RoleCommenter), app.deleteCardComment)
 
 	// Rotas de Membros e Convites
-	protected.Get("/boards/:id/members", app.getBoardMembers)
-	protected.Get("/boards/:id/invitable-users", app.getInvitableUsers)
-	protected.Post("/boards/:id/invite", app.inviteUserToBoard)
+	protected.Get("/boards/:id/members", app.requireAccess(resourceBoard, "id", RoleViewer), app.getBoardMembers)
+	protected.Put("/boards/:id/members/:memberId/role", app.requireAccess(resourceBoard, "id", RoleOwner), app.updateMemberRole)
+	protected.Get("/boards/:id/invitable-users", app.requireAccess(resourceBoard, "id", RoleOwner), app.getInvitableUsers)
+	protected.Post("/boards/:id/invite", app.requireAccess(resourceBoard, "id", RoleOwner), app.inviteUserToBoard)
 	protected.Post("/invitations/:id/respond", app.respondToInvitation)
 	protected.Delete("/boards/:boardId/members/:memberId", app.removeBoardMember)
 	protected.Post("/boards/:id/leave", app.leaveBoard)
+	protected.Post("/boards/:id/transfer-ownership", app.transferBoardOwnership)
 
 	// Notificações
 	protected.Get("/notifications", app.getNotifications)
@@ -755,12 +1009,15 @@ func (app *App) setupRoutes(fiberApp *fiber.App) {
 	// Rotas de Leitura e Edição para todos
 	protected.Get("/ligacoes", app.getLigacoes)
 	protected.Put("/ligacoes/:id", app.updateLigacao)
+	protected.Patch("/ligacoes/:id", app.patchLigacao)
 
 	protected.Get("/agenda/events", app.getAgendaEvents)
 	protected.Put("/agenda/events/:id", app.updateAgendaEvent)
+	protected.Patch("/agenda/events/:id", app.patchAgendaEvent)
 
 	protected.Get("/avaliacoes", app.getAvaliacoes)
 	protected.Put("/avaliacoes/:id", app.updateAvaliacao)
+	protected.Patch("/avaliacoes/:id", app.patchAvaliacao)
 
 	// Rotas de Contato para todos
 	protected.Get("/contatos/status", app.handleGetContatosStatus)
@@ -807,7 +1064,7 @@ WHERE email = 'email@kanban.local';
 func (app *App) getUsers(c *fiber.Ctx) error {
 	conn, err := app.db.Acquire(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro de conexão"})
+		return internalError(c, "erro de conexão")
 	}
 	defer conn.Release()
 	users := make([]User, 0)
@@ -824,7 +1081,7 @@ func (app *App) getUsers(c *fiber.Ctx) error {
     `
 	rows, err := conn.Query(context.Background(), query)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar usuários"})
+		return internalError(c, "erro ao buscar usuários")
 	}
 	defer rows.Close()
 	for rows.Next() {
@@ -841,24 +1098,32 @@ func (app *App) getUsers(c *fiber.Ctx) error {
 func (app *App) adminMiddleware(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 	if userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Usuário não autenticado"})
+		return unauthorized(c, "Usuário não autenticado")
 	}
 
-	var isAdmin bool
-	query := "SELECT COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) FROM auth.users WHERE id = $1"
-	err := app.db.QueryRow(context.Background(), query, userID).Scan(&isAdmin)
-
+	isAdmin, err := app.isUserAdmin(userID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar permissões de usuário"})
+		return internalError(c, "Erro ao verificar permissões de usuário")
 	}
 
 	if !isAdmin {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado. Esta ação requer privilégios de administrador."})
+		return accessDenied(c, "Acesso negado. Esta ação requer privilégios de administrador.")
 	}
 
 	return c.Next()
 }
 
+// verificar se user é admin
+func (app *App) isUserAdmin(userID string) (bool, error) {
+	var isAdmin bool
+	query := "SELECT COALESCE((raw_user_meta_data->>'is_admin')::boolean, false) FROM auth.users WHERE id = $1"
+	err := app.db.QueryRow(context.Background(), query, userID).Scan(&isAdmin)
+	if err != nil {
+		return false, err
+	}
+	return isAdmin, nil
+}
+
 // admin distribuir tarefa
 func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 	var payload struct {
@@ -867,11 +1132,11 @@ func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
 	if payload.ContatoID == "" || payload.AssigneeID == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "contato_id e assignee_id são obrigatórios"})
+		return badRequest(c, "contato_id e assignee_id são obrigatórios")
 	}
 
 	var userName, userAvatar sql.NullString
@@ -905,7 +1170,7 @@ func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 	err = app.db.QueryRow(context.Background(), query, payload.ContatoID, updatedBy, payload.AssigneeID, userName.String, userAvatar.String).Scan(&returnedId, &status, &anotacao, &updatedAt)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert para admin assumir contato: %v", err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao salvar a atribuição no banco de dados"})
+		return internalError(c, "Erro ao salvar a atribuição no banco de dados")
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -919,26 +1184,45 @@ func (app *App) handleAdminAssignContato(c *fiber.Ctx) error {
 
 // endpoint boards publicos
 func (app *App) getPublicBoards(c *fiber.Ctx) error {
-	query := `SELECT id, title, description, owner_id, created_at, updated_at, color, is_public
+	page, _ := strconv.Atoi(c.Query("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	pageSize, _ := strconv.Atoi(c.Query("page_size", "20"))
+	if pageSize <= 0 || pageSize > 100 {
+		pageSize = 20
+	}
+	sortColumn, ok := publicBoardSorts[c.Query("sort", "created_at")]
+	if !ok {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "sort", Code: "invalid_choice", Message: "valor inválido, use: created_at, updated_at, title"}}})
+	}
+	order := "DESC"
+	if strings.EqualFold(c.Query("order"), "asc") {
+		order = "ASC"
+	}
+
+	// o board padrão de cada time vem sempre primeiro
+	query := `SELECT ` + boardSelectColumns + `, COUNT(*) OVER ()
 			  FROM boards
-			  WHERE is_public = true
-			  ORDER BY created_at DESC LIMIT 1`
-	rows, err := app.db.Query(context.Background(), query)
+			  WHERE is_public = true AND deleted_at IS NULL AND ($1 = '' OR team = $1)
+			  ORDER BY is_default DESC, ` + sortColumn + ` ` + order + `, id
+			  LIMIT $2 OFFSET $3`
+	rows, err := app.db.Query(context.Background(), query, c.Query("team"), pageSize, (page-1)*pageSize)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar boards públicos"})
+		return internalError(c, "erro ao buscar boards públicos")
 	}
 	defer rows.Close()
 	boards := make([]Board, 0)
+	total := 0
 	for rows.Next() {
 		var board Board
-		if err := rows.Scan(&board.ID, &board.Title, &board.Description,
-			&board.OwnerID, &board.CreatedAt, &board.UpdatedAt,
-			&board.Color, &board.IsPublic); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board"})
+		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID, &board.CreatedAt,
+			&board.UpdatedAt, &board.Color, &board.IsPublic, &board.Team, &board.IsDefault, &board.Version, &total); err != nil {
+			return internalError(c, "erro ao ler board")
 		}
 		boards = append(boards, board)
 	}
-	return c.JSON(boards)
+	return c.JSON(fiber.Map{"boards": boards, "total": total, "page": page, "page_size": pageSize})
 }
 
 // endpoint boards privados
@@ -947,7 +1231,7 @@ func (app *App) getPrivateBoards(c *fiber.Ctx) error {
 
 	ownerQuery := `SELECT id, title, description, owner_id, created_at, updated_at, color, is_public
                    FROM boards
-                   WHERE owner_id = $1 AND is_public = false`
+                   WHERE owner_id = $1 AND is_public = false AND deleted_at IS NULL`
 
 	memberQuery := `SELECT b.id, b.title, b.description, b.owner_id, b.created_at, b.updated_at, b.color, b.is_public,
                            u.email as owner_email, -- Buscamos o email para usar no mapa
@@ -955,21 +1239,21 @@ func (app *App) getPrivateBoards(c *fiber.Ctx) error {
                     FROM boards b
                     JOIN board_memberships bm ON b.id = bm.board_id
                     JOIN auth.users u ON b.owner_id = u.id
-                    WHERE bm.user_id = $1 AND b.owner_id != $1`
+                    WHERE bm.user_id = $1 AND b.owner_id != $1 AND b.deleted_at IS NULL`
 
 	boards := make([]Board, 0)
 	boardIDs := make(map[int]bool)
 
 	rows, err := app.db.Query(context.Background(), ownerQuery, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar seus boards privados"})
+		return internalError(c, "erro ao buscar seus boards privados")
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var board Board
 		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID, &board.CreatedAt, &board.UpdatedAt, &board.Color, &board.IsPublic); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board privado"})
+			return internalError(c, "erro ao ler board privado")
 		}
 		if !boardIDs[board.ID] {
 			boards = append(boards, board)
@@ -980,7 +1264,7 @@ func (app *App) getPrivateBoards(c *fiber.Ctx) error {
 
 	rows, err = app.db.Query(context.Background(), memberQuery, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar boards compartilhados"})
+		return internalError(c, "erro ao buscar boards compartilhados")
 	}
 	defer rows.Close()
 
@@ -990,7 +1274,7 @@ func (app *App) getPrivateBoards(c *fiber.Ctx) error {
 		var ownerUsername string
 
 		if err := rows.Scan(&board.ID, &board.Title, &board.Description, &board.OwnerID, &board.CreatedAt, &board.UpdatedAt, &board.Color, &board.IsPublic, &ownerEmail, &ownerUsername); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler board compartilhado"})
+			return internalError(c, "erro ao ler board compartilhado")
 		}
 
 		if displayName, ok := userDisplayNameMap[ownerEmail]; ok {
@@ -1008,82 +1292,75 @@ func (app *App) getPrivateBoards(c *fiber.Ctx) error {
 	return c.JSON(boards)
 }
 
-// endpoint criar board
+// endpoint criar board (com template_id opcional)
 func (app *App) createBoard(c *fiber.Ctx) error {
-	var reqBoard Board
-	if err := c.BodyParser(&reqBoard); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "dados inválidos"})
+	var payload struct {
+		Board
+		TemplateID *int `json:"template_id"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
 	}
+	reqBoard := payload.Board
 	userID := c.Locals("userID").(string)
-	if reqBoard.Title == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "O título do quadro é obrigatório"})
+	if err := validateBoard(&reqBoard); err != nil {
+		return validationFailed(c, err)
 	}
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao iniciar transação"})
+		return internalError(c, "erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
-	query := `INSERT INTO boards (title, description, owner_id, is_public, color)
-			  VALUES ($1, $2, $3, $4, $5)
-			  RETURNING id, created_at, updated_at`
+	template, err := app.templateForNewBoard(context.Background(), tx, payload.TemplateID, userID)
+	if err != nil {
+		if verr, ok := err.(*ValidationError); ok {
+			return validationFailed(c, verr)
+		}
+		return internalError(c, "erro ao buscar template")
+	}
+	if reqBoard.Color == "" {
+		reqBoard.Color = template.Color
+	}
+	query := `INSERT INTO boards (title, description, owner_id, is_public, color, team)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING id, created_at, updated_at, version`
 	err = tx.QueryRow(context.Background(), query,
-		reqBoard.Title, reqBoard.Description, userID, reqBoard.IsPublic, reqBoard.Color).Scan(&reqBoard.ID, &reqBoard.CreatedAt, &reqBoard.UpdatedAt)
+		reqBoard.Title, reqBoard.Description, userID, reqBoard.IsPublic, reqBoard.Color, reqBoard.Team).Scan(&reqBoard.ID, &reqBoard.CreatedAt, &reqBoard.UpdatedAt, &reqBoard.Version)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao criar board"})
+		return internalError(c, "erro ao criar board")
 	}
 	reqBoard.OwnerID = userID
-	defaultColumns := []struct {
-		Title string
-		Color string
-	}{
-		{"A Fazer", "#58a6ff"},
-		{"Em Andamento", "#d29922"},
-		{"Concluído", "#3fb950"},
-	}
-	colQuery := `INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`
-	for i, col := range defaultColumns {
-		if _, err := tx.Exec(context.Background(), colQuery, reqBoard.ID, col.Title, i, col.Color); err != nil {
-			log.Printf("Erro ao criar coluna padrão '%s' para o board %d: %v", col.Title, reqBoard.ID, err)
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar colunas padrão"})
-		}
+	reqBoard.IsDefault = false
+	if err := app.applyTemplate(context.Background(), tx, reqBoard.ID, userID, template); err != nil {
+		log.Printf("Erro ao aplicar template no board %d: %v", reqBoard.ID, err)
+		return internalError(c, "erro ao criar colunas do template")
+	}
+	if _, err := applyBoardVisibility(context.Background(), tx, &reqBoard); err != nil {
+		log.Printf("Erro ao criar colunas de status do board %d: %v", reqBoard.ID, err)
+		return internalError(c, "erro ao criar colunas de status")
 	}
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao confirmar criação do board"})
+		return internalError(c, "erro ao confirmar criação do board")
 	}
 	return c.Status(201).JSON(reqBoard)
 }
 
 // endpoint colunas
 func (app *App) getColumns(c *fiber.Ctx) error {
-	boardID, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
-	}
-	userID := c.Locals("userID").(string)
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
-	}
-	var isPublic bool
-	app.db.QueryRow(context.Background(), "SELECT is_public FROM boards WHERE id = $1", boardID).Scan(&isPublic)
-
-	if isPublic {
-		return app.getPublicBoardColumns(c, boardID)
-	}
-
-	query := `SELECT id, board_id, title, position, COALESCE(color, '#e4e6ea') as color
-			  FROM columns WHERE board_id = $1 ORDER BY position`
+	boardID := authorizedBoardID(c)
+	query := `SELECT ` + columnSelectColumns + `
+			  FROM columns WHERE board_id = $1 AND deleted_at IS NULL ORDER BY position`
 	rows, err := app.db.Query(context.Background(), query, boardID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar colunas"})
+		return internalError(c, "erro ao buscar colunas")
 	}
 	defer rows.Close()
 
 	columns := make([]Column, 0)
 	for rows.Next() {
 		var col Column
-		if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna"})
+		if err := scanColumn(rows, &col); err != nil {
+			return internalError(c, "erro ao ler dados da coluna")
 		}
 		columns = append(columns, col)
 	}
@@ -1092,27 +1369,19 @@ func (app *App) getColumns(c *fiber.Ctx) error {
 
 // endpoint reordenar colunas
 func (app *App) reorderColumns(c *fiber.Ctx) error {
-	boardID, err := strconv.Atoi(c.Params("id"))
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de board inválido"})
-	}
+	boardID := authorizedBoardID(c)
 	userID := c.Locals("userID").(string)
 
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
-	}
-
 	var payload struct {
 		OrderedColumnIDs []int `json:"ordered_column_ids"`
 	}
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+		return internalError(c, "Erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
 
@@ -1121,12 +1390,12 @@ func (app *App) reorderColumns(c *fiber.Ctx) error {
 		_, err := tx.Exec(context.Background(), query, i, colID, boardID)
 		if err != nil {
 			log.Printf("Erro ao reordenar coluna ID %d: %v", colID, err)
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar uma das colunas"})
+			return internalError(c, "Erro ao reordenar uma das colunas")
 		}
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a reordenação"})
+		return internalError(c, "Erro ao confirmar a reordenação")
 	}
 
 	app.broadcast(boardID, WsMessage{
@@ -1138,115 +1407,50 @@ func (app *App) reorderColumns(c *fiber.Ctx) error {
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
-// pegar boards publicos
-func (app *App) getPublicBoardColumns(c *fiber.Ctx, boardID int) error {
-	tx, err := app.db.Begin(context.Background())
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação de verificação"})
-	}
-	defer tx.Rollback(context.Background())
-	query := `SELECT id, board_id, title, position, COALESCE(color, '#e4e6ea') as color
-			  FROM columns WHERE board_id = $1 ORDER BY position`
-	rows, err := tx.Query(context.Background(), query, boardID)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar colunas"})
-	}
-	columns := make([]Column, 0)
-	foundSolucionado := false
-	foundNaoSolucionado := false
-	maxPosition := -1
-	for rows.Next() {
-		var col Column
-		if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-			rows.Close()
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna"})
-		}
-		titleLower := strings.ToLower(col.Title)
-		if titleLower == "solucionado" {
-			foundSolucionado = true
-		}
-		if titleLower == "não solucionado" {
-			foundNaoSolucionado = true
-		}
-		if col.Position > maxPosition {
-			maxPosition = col.Position
-		}
-		columns = append(columns, col)
-	}
-	rows.Close()
-	if !foundSolucionado {
-		maxPosition++
-		_, err = tx.Exec(context.Background(),
-			`INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`,
-			boardID, "Solucionado", maxPosition, "#3fb950")
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar coluna 'Solucionado'"})
-		}
-	}
-	if !foundNaoSolucionado {
-		maxPosition++
-		_, err = tx.Exec(context.Background(),
-			`INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4)`,
-			boardID, "Não Solucionado", maxPosition, "#f85149")
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao criar coluna 'Não Solucionado'"})
-		}
-	}
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar criação de colunas de status"})
-	}
-	if !foundSolucionado || !foundNaoSolucionado {
-		rows, err = app.db.Query(context.Background(), query, boardID)
-		if err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao re-buscar colunas após correção"})
-		}
-		defer rows.Close()
-		columns = make([]Column, 0)
-		for rows.Next() {
-			var col Column
-			if err := rows.Scan(&col.ID, &col.BoardID, &col.Title, &col.Position, &col.Color); err != nil {
-				return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados da coluna após correção"})
-			}
-			columns = append(columns, col)
-		}
-	}
-	return c.JSON(columns)
-}
-
-// endpoint cards
+// endpoint cards; arquivados só com ?include_archived=true
 func (app *App) getCards(c *fiber.Ctx) error {
 	columnID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de coluna inválido"})
+		return badRequest(c, "ID de coluna inválido")
 	}
-	userID := c.Locals("userID").(string)
-	boardID, err := app.getBoardIDFromColumn(columnID)
+	includeArchived := c.QueryBool("include_archived")
+	labelFilter, matchAll, err := parseLabelFilter(c)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada."})
+		return validationFailed(c, err)
 	}
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado a este quadro."})
+	// com label_match=all o card precisa ter todas as etiquetas pedidas
+	required := 0
+	if len(labelFilter) > 0 {
+		required = 1
+		if matchAll {
+			required = len(labelFilter)
+		}
 	}
 	rows, err := app.db.Query(context.Background(), `
-		SELECT id, column_id, title, COALESCE(description, '') as description,
-			   COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
-			   due_date, position, created_at, updated_at, completed_at
-		FROM cards WHERE column_id = $1 ORDER BY position`, columnID)
+		SELECT `+cardSelectColumns+`
+		FROM cards WHERE column_id = $1 AND deleted_at IS NULL AND ($2 OR archived_at IS NULL)
+		  AND ($4 = 0 OR (SELECT COUNT(*) FROM card_labels cl WHERE cl.card_id = cards.id AND cl.label_id = ANY($3)) >= $4)
+		ORDER BY archived_at IS NOT NULL, position`, columnID, includeArchived, nonNilInts(labelFilter), required)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao buscar cards"})
+		return internalError(c, "erro ao buscar cards")
 	}
 	defer rows.Close()
 	cards := make([]Card, 0)
 	for rows.Next() {
 		var card Card
-		if err := rows.Scan(&card.ID, &card.ColumnID, &card.Title, &card.Description,
-			&card.AssignedTo, &card.Priority, &card.DueDate, &card.Position,
-			&card.CreatedAt, &card.UpdatedAt, &card.CompletedAt); err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "erro ao ler dados do card"})
+		if err := scanCard(rows, &card); err != nil {
+			return internalError(c, "erro ao ler dados do card")
 		}
 		cards = append(cards, card)
 	}
+	rows.Close()
+	refs := make([]*Card, len(cards))
+	for i := range cards {
+		refs[i] = &cards[i]
+	}
+	if err := app.attachCardDetails(context.Background(), refs); err != nil {
+		return internalError(c, "erro ao buscar etiquetas e responsáveis")
+	}
 	return c.JSON(cards)
 }
 
@@ -1277,189 +1481,242 @@ func (app *App) createNotification(tx pgx.Tx, n Notification) error {
 func (app *App) createCard(c *fiber.Ctx) error {
 	columnID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "ID da coluna inválido"})
+		return badRequest(c, "ID da coluna inválido")
 	}
 	userID := c.Locals("userID").(string)
-	boardID, err := app.getBoardIDFromColumn(columnID)
-	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Coluna não encontrada"})
-	}
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado"})
-	}
+	boardID := authorizedBoardID(c)
 	var card Card
 	if err := c.BodyParser(&card); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados de card inválidos"})
+		return invalidBody(c)
 	}
 	card.ColumnID = columnID
+	if card.Priority == "" {
+		card.Priority = "media"
+	}
+	if err := validateCard(&card); err != nil {
+		return validationFailed(c, err)
+	}
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+		return internalError(c, "Erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
 	var maxPos sql.NullInt64
-	tx.QueryRow(context.Background(), "SELECT MAX(position) FROM cards WHERE column_id = $1", columnID).Scan(&maxPos)
+	tx.QueryRow(context.Background(), "SELECT MAX(position) FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL", columnID).Scan(&maxPos)
 	card.Position = int(maxPos.Int64) + 1
-	query := `INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
-	err = tx.QueryRow(context.Background(), query, card.ColumnID, card.Title, card.Description, card.AssignedTo, card.Priority, card.DueDate, card.Position).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt)
+	legacy, hasLegacyComments := parseLegacyDescription(card.Description)
+	if hasLegacyComments {
+		card.Description = ""
+	}
+	assigneeIDs, problems, err := app.requestedAssignees(context.Background(), tx, boardID, card.AssigneeIDs, card.AssignedTo)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar card"})
+		return internalError(c, "Erro ao validar responsáveis")
 	}
-	if card.AssignedTo != "" {
-		assigneeID, err := app.getUserIDByUsername(card.AssignedTo)
-		if err == nil {
-			notification := Notification{
-				UserID:         assigneeID,
-				Type:           "new_task_assigned",
-				Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", card.Title),
-				RelatedBoardID: &boardID,
-				RelatedCardID:  &card.ID,
-			}
-			app.createNotification(tx, notification)
+	if len(problems) > 0 {
+		return validationFailed(c, &ValidationError{Fields: problems})
+	}
+	card.AssigneeIDs = nil
+	query := `INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position) VALUES ($1, $2, $3, '', $4, $5, $6) RETURNING id, created_at, updated_at, version`
+	err = tx.QueryRow(context.Background(), query, card.ColumnID, card.Title, card.Description, card.Priority, card.DueDate, card.Position).Scan(&card.ID, &card.CreatedAt, &card.UpdatedAt, &card.Version)
+	if err != nil {
+		return internalError(c, "Erro ao criar card")
+	}
+	if hasLegacyComments {
+		if _, err := app.importLegacyComments(context.Background(), tx, card.ID, legacy, card.CreatedAt, make(map[string]*string)); err != nil {
+			log.Printf("Erro ao gravar comentários do card %d: %v", card.ID, err)
+			return internalError(c, "Erro ao gravar comentários")
+		}
+	}
+	if err := app.recordCardEvent(tx, card.ID, boardID, userID, CardEventCreated, "", nil, fiber.Map{"title": card.Title, "column_id": card.ColumnID}); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", card.ID, err)
+		return internalError(c, "Erro ao registrar histórico")
+	}
+	assignees, added, changed, err := app.updateCardAssignees(context.Background(), tx, card.ID, boardID, userID, assigneeIDs)
+	if err != nil {
+		log.Printf("Erro ao atribuir responsáveis do card %d: %v", card.ID, err)
+		return internalError(c, "Erro ao atribuir responsáveis")
+	}
+	card.Assignees, card.AssignedTo = assignees, ""
+	if changed {
+		if err := tx.QueryRow(context.Background(), `UPDATE cards SET assigned_to = `+assignedToFromAssignees+`
+			WHERE id = $1 RETURNING assigned_to`, card.ID).Scan(&card.AssignedTo); err != nil {
+			return internalError(c, "Erro ao atribuir responsáveis")
 		}
+		app.notifyNewAssignees(context.Background(), tx, added, boardID, card.ID, card.Title)
 	}
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar criação"})
+		return internalError(c, "Erro ao confirmar criação")
 	}
 	app.broadcast(boardID, WsMessage{Type: "CARD_CREATED", Payload: card})
 	return c.Status(201).JSON(card)
 }
 
+// o PUT não troca a coluna: isso é feito pelo /cards/move, que valida o board e reordena as posições
+func validateColumnUnchanged(current, requested int) error {
+	var v Validator
+	if requested != 0 && requested != current {
+		v.Add("column_id", "use_move", "para mudar de coluna use POST /cards/move")
+	}
+	return v.Err()
+}
+
+// mover para outro board só quando pedido explicitamente
+func validateMoveTarget(oldBoardID, newBoardID int, allowCrossBoard bool) error {
+	var v Validator
+	if newBoardID != oldBoardID && !allowCrossBoard {
+		v.Add("new_column_id", "cross_board", "a coluna de destino pertence a outro quadro")
+	}
+	return v.Err()
+}
+
 // endpoint att card
 func (app *App) updateCard(c *fiber.Ctx) error {
 	cardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "ID do card inválido"})
+		return badRequest(c, "ID do card inválido")
 	}
 
+	userID := c.Locals("userID").(string)
+
 	var payload Card
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados de card inválidos"})
+		return invalidBody(c)
+	}
+	if payload.Priority == "" {
+		payload.Priority = "media"
+	}
+	if err := validateCard(&payload); err != nil {
+		return validationFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, payload.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
 	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+		return internalError(c, "Erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
 
 	var existingCard Card
-	err = tx.QueryRow(context.Background(), "SELECT column_id, assigned_to FROM cards WHERE id = $1", cardID).Scan(&existingCard.ColumnID, &existingCard.AssignedTo)
+	var boardID int
+	err = tx.QueryRow(context.Background(), `
+		SELECT ca.id, ca.column_id, ca.title, COALESCE(ca.description, ''), COALESCE(ca.assigned_to, ''),
+			   COALESCE(ca.priority, 'media'), ca.due_date, ca.completed_at, ca.version, col.board_id
+		FROM cards ca JOIN columns col ON ca.column_id = col.id
+		WHERE ca.id = $1
+		FOR UPDATE OF ca`, cardID).Scan(
+		&existingCard.ID, &existingCard.ColumnID, &existingCard.Title, &existingCard.Description, &existingCard.AssignedTo,
+		&existingCard.Priority, &existingCard.DueDate, &existingCard.CompletedAt, &existingCard.Version, &boardID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c.Status(404).JSON(fiber.Map{"error": "Tarefa não encontrada"})
+			return notFound(c, "Tarefa não encontrada")
+		}
+		return internalError(c, "Erro ao buscar tarefa original")
+	}
+
+	if expectedVersion != 0 && expectedVersion != existingCard.Version {
+		current, err := app.fetchCard(tx, cardID)
+		if err != nil {
+			return internalError(c, "Erro ao buscar tarefa atual")
 		}
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar tarefa original"})
+		return versionConflict(c, current.Version, current)
 	}
 
-	if payload.ColumnID == 0 {
-		payload.ColumnID = existingCard.ColumnID
+	if err := validateColumnUnchanged(existingCard.ColumnID, payload.ColumnID); err != nil {
+		return validationFailed(c, err)
+	}
+	payload.ColumnID = existingCard.ColumnID
+	// clientes antigos ainda gravam os comentários na descrição
+	if payload.Description, err = app.absorbLegacyDescription(context.Background(), tx, cardID, payload.Description); err != nil {
+		log.Printf("Erro ao gravar comentários do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao gravar comentários")
+	}
+
+	// responsáveis só mudam com assignee_ids ou com um assigned_to diferente (clientes antigos)
+	var assignees []UserSummary
+	var addedAssignees []string
+	assigneesChanged := false
+	if payload.AssigneeIDs != nil || payload.AssignedTo != existingCard.AssignedTo {
+		assigneeIDs, problems, err := app.requestedAssignees(context.Background(), tx, boardID, payload.AssigneeIDs, payload.AssignedTo)
+		if err != nil {
+			return internalError(c, "Erro ao validar responsáveis")
+		}
+		if len(problems) > 0 {
+			return validationFailed(c, &ValidationError{Fields: problems})
+		}
+		assignees, addedAssignees, assigneesChanged, err = app.updateCardAssignees(context.Background(), tx, cardID, boardID, userID, assigneeIDs)
+		if err != nil {
+			log.Printf("Erro ao atribuir responsáveis do card %d: %v", cardID, err)
+			return internalError(c, "Erro ao atribuir responsáveis")
+		}
+	}
+	assignedTo := "assigned_to"
+	if assigneesChanged {
+		assignedTo = assignedToFromAssignees
 	}
 
 	query := `
 		UPDATE cards SET 
 			title = $1, 
 			description = $2, 
-			assigned_to = $3, 
-			priority = $4, 
-			due_date = $5, 
-			column_id = $6,
-			completed_at = $7,
-			updated_at = NOW() 
-		WHERE id = $8`
+			assigned_to = ` + assignedTo + `, 
+			priority = $3, 
+			due_date = $4, 
+			completed_at = $5,
+			updated_at = NOW(),
+			version = version + 1
+		WHERE id = $6
+		RETURNING ` + cardSelectColumns
 
-	_, err = tx.Exec(context.Background(), query,
-		payload.Title, payload.Description, payload.AssignedTo, payload.Priority,
-		payload.DueDate, payload.ColumnID, payload.CompletedAt, cardID)
+	var updatedCard Card
+	err = scanCard(tx.QueryRow(context.Background(), query,
+		payload.Title, payload.Description, payload.Priority,
+		payload.DueDate, payload.CompletedAt, cardID), &updatedCard)
 
 	if err != nil {
 		log.Printf("Erro ao atualizar card no DB: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar card no banco de dados"})
+		return internalError(c, "Erro ao atualizar card no banco de dados")
 	}
 
-	if payload.AssignedTo != "" && payload.AssignedTo != existingCard.AssignedTo {
-		boardID, err := app.getBoardIDFromCard(cardID)
-		if err == nil {
-			assigneeID, err := app.getUserIDByUsername(payload.AssignedTo)
-			if err == nil {
-				notification := Notification{
-					UserID:         assigneeID,
-					Type:           "new_task_assigned",
-					Message:        fmt.Sprintf("Você foi atribuído à tarefa: %s", payload.Title),
-					RelatedBoardID: &boardID,
-					RelatedCardID:  &cardID,
-				}
-				app.createNotification(tx, notification)
-			}
-		}
+	payload.ID = cardID
+	if err := app.recordCardChanges(tx, boardID, userID, existingCard, payload); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
 	}
 
+	if assignees != nil {
+		updatedCard.Assignees = assignees
+	}
+	app.notifyNewAssignees(context.Background(), tx, addedAssignees, boardID, cardID, updatedCard.Title)
+
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar atualização"})
+		return internalError(c, "Erro ao confirmar atualização")
 	}
 
 	// Envia a atualização para outros clientes via WebSocket
-	go func() {
-		boardID, err := app.getBoardIDFromCard(cardID)
-		if err == nil {
-			var updatedCard Card
-			selectQuery := `SELECT id, column_id, title, COALESCE(description, '') as description, COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority, due_date, position, created_at, updated_at, completed_at FROM cards WHERE id = $1`
-			err := app.db.QueryRow(context.Background(), selectQuery, cardID).Scan(&updatedCard.ID, &updatedCard.ColumnID, &updatedCard.Title, &updatedCard.Description, &updatedCard.AssignedTo, &updatedCard.Priority, &updatedCard.DueDate, &updatedCard.Position, &updatedCard.CreatedAt, &updatedCard.UpdatedAt, &updatedCard.CompletedAt)
-			if err != nil {
-				log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
-				return
-			}
-			app.broadcast(boardID, WsMessage{Type: "CARD_UPDATED", Payload: updatedCard})
-		}
-	}()
+	app.broadcast(boardID, WsMessage{Type: "CARD_UPDATED", Payload: updatedCard})
 
-	return c.Status(200).JSON(fiber.Map{"status": "updated"})
+	setVersionETag(c, updatedCard.Version)
+	return c.Status(200).JSON(updatedCard)
 }
 
 // permissao dos boards
 func (app *App) checkBoardPermission(userID string, boardID int) (bool, error) {
-	var isPublic bool
-	err := app.db.QueryRow(context.Background(), "SELECT is_public FROM boards WHERE id = $1", boardID).Scan(&isPublic)
+	role, err := app.boardRole(userID, boardID)
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
-		}
-		return false, err
-	}
-	if isPublic {
-		return true, nil
-	}
-
-	var ownerID string
-	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return false, nil
-		}
 		return false, err
 	}
-	if ownerID == userID {
-		return true, nil
-	}
-
-	var memberCount int
-	err = app.db.QueryRow(context.Background(),
-		"SELECT COUNT(*) FROM board_memberships WHERE board_id = $1 AND user_id = $2",
-		boardID, userID).Scan(&memberCount)
-	if err != nil {
-		return false, err
-	}
-	if memberCount > 0 {
-		return true, nil
-	}
-	return false, nil
+	return role != "", nil
 }
 
 // pegar id do board por coluna
 func (app *App) getBoardIDFromColumn(columnID int) (int, error) {
 	var boardID int
-	query := `SELECT board_id FROM columns WHERE id = $1`
+	query := `SELECT c.board_id FROM columns c
+			  INNER JOIN boards b ON b.id = c.board_id
+			  WHERE c.id = $1 AND c.deleted_at IS NULL AND b.deleted_at IS NULL`
 	err := app.db.QueryRow(context.Background(), query, columnID).Scan(&boardID)
 	if err != nil {
 		return 0, err
@@ -1470,16 +1727,42 @@ func (app *App) getBoardIDFromColumn(columnID int) (int, error) {
 // endpoint deletar card
 func (app *App) deleteCard(c *fiber.Ctx) error {
 	cardID, _ := strconv.Atoi(c.Params("id"))
-	boardID, err := app.getBoardIDFromCard(cardID)
+	userID := c.Locals("userID").(string)
+	tx, err := app.db.Begin(context.Background())
 	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
 	}
-	_, err = app.db.Exec(context.Background(), `DELETE FROM cards WHERE id = $1`, cardID)
+	defer tx.Rollback(context.Background())
+	var title string
+	var boardID, columnID, position int
+	err = tx.QueryRow(context.Background(),
+		`SELECT ca.title, col.board_id, ca.column_id, ca.position FROM cards ca JOIN columns col ON ca.column_id = col.id
+		 WHERE ca.id = $1 AND ca.deleted_at IS NULL FOR UPDATE OF ca`,
+		cardID).Scan(&title, &boardID, &columnID, &position)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "erro ao deletar card"})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Card não encontrado")
+		}
+		return internalError(c, "erro ao buscar card")
+	}
+	if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventDeleted, "", fiber.Map{"title": title}, nil); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
 	}
-	if boardID != 0 {
-		app.broadcast(boardID, WsMessage{Type: "CARD_DELETED", Payload: fiber.Map{"card_id": cardID}})
+	// vai para a lixeira; o purge remove de vez após o período de retenção
+	_, err = tx.Exec(context.Background(), `UPDATE cards SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1`, cardID, userID)
+	if err != nil {
+		return internalError(c, "erro ao deletar card")
+	}
+	_, err = tx.Exec(context.Background(),
+		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2 AND deleted_at IS NULL", columnID, position)
+	if err != nil {
+		return internalError(c, "Erro ao reordenar coluna")
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return internalError(c, "Erro ao confirmar a exclusão")
 	}
+	app.broadcast(boardID, WsMessage{Type: "CARD_DELETED", Payload: fiber.Map{"card_id": cardID}})
 	return c.Status(200).JSON(fiber.Map{"status": "deleted"})
 }
 
@@ -1491,53 +1774,100 @@ func (app *App) moveCard(c *fiber.Ctx) error {
 		CardID      int `json:"card_id"`
 		NewColumnID int `json:"new_column_id"`
 		NewPosition int `json:"new_position"`
+		// mover para outro board só quando pedido explicitamente
+		AllowCrossBoard bool `json:"allow_cross_board"`
 	}
 
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+		return invalidBody(c)
+	}
+	var v Validator
+	if payload.NewColumnID <= 0 {
+		v.Add("new_column_id", "required", "campo obrigatório")
+	}
+	v.NonNegative("new_position", payload.NewPosition)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
 	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+		return internalError(c, "Erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
 
-	var oldColumnID, oldPosition int
+	var oldColumnID, oldPosition, oldBoardID, newBoardID int
 	var oldColumnTitle, newColumnTitle string
+	var oldCompletedAt, newCompletedAt *time.Time
 
+	// trava o card antes de recalcular posições
 	err = tx.QueryRow(context.Background(),
-		`SELECT c.column_id, c.position, col.title FROM cards c
+		`SELECT c.column_id, c.position, col.title, col.board_id, c.completed_at FROM cards c
 		 JOIN columns col ON c.column_id = col.id
-		 WHERE c.id = $1`, payload.CardID,
-	).Scan(&oldColumnID, &oldPosition, &oldColumnTitle)
+		 WHERE c.id = $1 AND c.deleted_at IS NULL FOR UPDATE OF c`, payload.CardID,
+	).Scan(&oldColumnID, &oldPosition, &oldColumnTitle, &oldBoardID, &oldCompletedAt)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Card não encontrado"})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Card não encontrado")
+		}
+		return internalError(c, "Erro ao buscar o card")
+	}
+	// o card pode ter mudado de board depois do requireBodyAccess
+	if oldBoardID != authorizedBoardID(c) && !app.requireBoardRole(c, oldBoardID, RoleEditor) {
+		return nil
 	}
 
-	err = tx.QueryRow(context.Background(), "SELECT title FROM columns WHERE id = $1", payload.NewColumnID).Scan(&newColumnTitle)
+	// colunas de origem e destino travadas em ordem de id, como nas operações em lote
+	if _, err := tx.Exec(context.Background(),
+		"SELECT id FROM columns WHERE id = ANY($1) ORDER BY id FOR UPDATE", uniqueInts([]int{oldColumnID, payload.NewColumnID})); err != nil {
+		return internalError(c, "Erro ao travar as colunas")
+	}
+
+	err = tx.QueryRow(context.Background(), "SELECT title, board_id FROM columns WHERE id = $1 AND deleted_at IS NULL", payload.NewColumnID).Scan(&newColumnTitle, &newBoardID)
 	if err != nil {
-		return c.Status(404).JSON(fiber.Map{"error": "Coluna de destino não encontrada"})
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Coluna de destino não encontrada")
+		}
+		return internalError(c, "Erro ao buscar a coluna de destino")
+	}
+	if err := validateMoveTarget(oldBoardID, newBoardID, payload.AllowCrossBoard); err != nil {
+		return validationFailed(c, err)
+	}
+	if newBoardID != oldBoardID {
+		if !app.requireBoardRole(c, newBoardID, RoleEditor) {
+			return nil
+		}
+	}
+
+	if newBoardID != oldBoardID {
+		err = app.dropForeignCardLabels(context.Background(), tx, payload.CardID, newBoardID, userID)
+		if err == nil {
+			_, err = app.dropAssigneesWithoutAccess(context.Background(), tx, payload.CardID, newBoardID, userID)
+		}
+		if err != nil {
+			log.Printf("Erro ao ajustar etiquetas e responsáveis do card %d: %v", payload.CardID, err)
+			return internalError(c, "Erro ao mover o card")
+		}
 	}
 
 	_, err = tx.Exec(context.Background(),
-		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2",
+		"UPDATE cards SET position = position - 1 WHERE column_id = $1 AND position > $2 AND deleted_at IS NULL",
 		oldColumnID, oldPosition,
 	)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar coluna antiga"})
+		return internalError(c, "Erro ao reordenar coluna antiga")
 	}
 
 	_, err = tx.Exec(context.Background(),
-		"UPDATE cards SET position = position + 1 WHERE column_id = $1 AND position >= $2",
+		"UPDATE cards SET position = position + 1 WHERE column_id = $1 AND position >= $2 AND deleted_at IS NULL",
 		payload.NewColumnID, payload.NewPosition,
 	)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao reordenar nova coluna"})
+		return internalError(c, "Erro ao reordenar nova coluna")
 	}
 
-	isEnteringFinalColumn := strings.ToLower(newColumnTitle) == "solucionado" || strings.ToLower(newColumnTitle) == "não solucionado"
-	isLeavingFinalColumn := strings.ToLower(oldColumnTitle) == "solucionado" || strings.ToLower(oldColumnTitle) == "não solucionado"
+	isEnteringFinalColumn := isSystemColumnTitle(newColumnTitle)
+	isLeavingFinalColumn := isSystemColumnTitle(oldColumnTitle)
 
 	completedAtUpdateQuery := ""
 	if isEnteringFinalColumn && !isLeavingFinalColumn {
@@ -1547,49 +1877,47 @@ func (app *App) moveCard(c *fiber.Ctx) error {
 	}
 
 	updateQuery := fmt.Sprintf(
-		"UPDATE cards SET column_id = $1, position = $2, updated_at = NOW() %s WHERE id = $3",
+		"UPDATE cards SET column_id = $1, position = $2, updated_at = NOW(), version = version + 1 %s WHERE id = $3 RETURNING completed_at",
 		completedAtUpdateQuery,
 	)
-	_, err = tx.Exec(context.Background(), updateQuery, payload.NewColumnID, payload.NewPosition, payload.CardID)
+	err = tx.QueryRow(context.Background(), updateQuery, payload.NewColumnID, payload.NewPosition, payload.CardID).Scan(&newCompletedAt)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao mover o card"})
-	}
-
-	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar a movimentação"})
+		return internalError(c, "Erro ao mover o card")
 	}
 
-	go func() {
-		var updatedCard Card
-		selectQuery := `
-			SELECT id, column_id, title, COALESCE(description, '') as description,
-				   COALESCE(assigned_to, '') as assigned_to, COALESCE(priority, 'media') as priority,
-				   due_date, position, created_at, updated_at, completed_at
-			FROM cards WHERE id = $1`
-
-		err := app.db.QueryRow(context.Background(), selectQuery, payload.CardID).Scan(
-			&updatedCard.ID, &updatedCard.ColumnID, &updatedCard.Title, &updatedCard.Description,
-			&updatedCard.AssignedTo, &updatedCard.Priority, &updatedCard.DueDate, &updatedCard.Position,
-			&updatedCard.CreatedAt, &updatedCard.UpdatedAt, &updatedCard.CompletedAt,
-		)
-
+	if oldColumnID != payload.NewColumnID {
+		err = app.recordCardEvent(tx, payload.CardID, newBoardID, userID, CardEventMoved, "column_id",
+			fiber.Map{"column_id": oldColumnID, "title": oldColumnTitle, "board_id": oldBoardID},
+			fiber.Map{"column_id": payload.NewColumnID, "title": newColumnTitle, "board_id": newBoardID})
+		if err == nil {
+			err = app.recordCompletionChange(tx, payload.CardID, newBoardID, userID, oldCompletedAt, newCompletedAt)
+		}
 		if err != nil {
-			log.Printf("Erro ao buscar card atualizado para broadcast: %v", err)
-			return
+			log.Printf("Erro ao registrar histórico do card %d: %v", payload.CardID, err)
+			return internalError(c, "Erro ao registrar histórico")
 		}
+	}
 
-		boardID, boardIDErr := app.getBoardIDFromColumn(payload.NewColumnID)
-		if boardIDErr == nil && boardID != 0 {
-			app.broadcast(boardID, WsMessage{
-				SenderID: userID,
-				Type:     "CARD_MOVED",
-				Payload: fiber.Map{
-					"card":          updatedCard,
-					"old_column_id": oldColumnID,
-				},
-			})
-		}
-	}()
+	updatedCard, err := app.fetchCard(tx, payload.CardID)
+	if err != nil {
+		return internalError(c, "Erro ao buscar o card movido")
+	}
+	if err := tx.Commit(context.Background()); err != nil {
+		return internalError(c, "Erro ao confirmar a movimentação")
+	}
+
+	// síncrono: o seq do evento segue a ordem dos commits
+	if oldBoardID != newBoardID {
+		app.broadcast(oldBoardID, WsMessage{SenderID: userID, Type: "CARD_DELETED", Payload: fiber.Map{"card_id": payload.CardID}})
+	}
+	app.broadcast(newBoardID, WsMessage{
+		SenderID: userID,
+		Type:     "CARD_MOVED",
+		Payload: fiber.Map{
+			"card":          updatedCard,
+			"old_column_id": oldColumnID,
+		},
+	})
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -1598,7 +1926,7 @@ func (app *App) moveCard(c *fiber.Ctx) error {
 func (app *App) leaveBoard(c *fiber.Ctx) error {
 	boardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do quadro inválido"})
+		return badRequest(c, "ID do quadro inválido")
 	}
 	userID := c.Locals("userID").(string)
 
@@ -1606,19 +1934,20 @@ func (app *App) leaveBoard(c *fiber.Ctx) error {
 	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
+			return notFound(c, "Quadro não encontrado")
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao verificar o quadro"})
+		return internalError(c, "Erro ao verificar o quadro")
 	}
 
 	if ownerID == userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "O dono do quadro não pode sair. Use a opção de excluir o quadro."})
+		return accessDenied(c, "O dono do quadro não pode sair. Transfira a propriedade ou exclua o quadro.")
 	}
 
 	_, err = app.db.Exec(context.Background(), "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Falha ao sair do quadro."})
+		return internalError(c, "Falha ao sair do quadro.")
 	}
+	app.disconnectBoardClients(boardID, userID)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -1627,7 +1956,7 @@ func (app *App) leaveBoard(c *fiber.Ctx) error {
 func (app *App) getInvitableUsers(c *fiber.Ctx) error {
 	boardID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de quadro inválido"})
+		return badRequest(c, "ID de quadro inválido")
 	}
 	currentUserID := c.Locals("userID").(string)
 
@@ -1650,7 +1979,7 @@ func (app *App) getInvitableUsers(c *fiber.Ctx) error {
 	rows, err := app.db.Query(context.Background(), query, boardID, currentUserID)
 	if err != nil {
 		log.Printf("Erro ao buscar usuários convidáveis: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar usuários"})
+		return internalError(c, "Erro ao buscar usuários")
 	}
 	defer rows.Close()
 
@@ -1669,36 +1998,47 @@ func (app *App) getInvitableUsers(c *fiber.Ctx) error {
 
 // convidar user
 func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
-	boardID, _ := strconv.Atoi(c.Params("id"))
+	boardID := authorizedBoardID(c)
 	inviterID := c.Locals("userID").(string)
 	var payload struct {
 		InviteeID string `json:"invitee_id"`
+		Role      string `json:"role"`
 	}
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Payload inválido"})
+		return invalidBody(c)
+	}
+	if payload.Role == "" {
+		payload.Role = string(RoleEditor)
+	}
+	var v Validator
+	v.Required("invitee_id", payload.InviteeID, maxShortTextLength)
+	v.OneOf("role", payload.Role, memberRoles)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
 	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao iniciar transação"})
+		return internalError(c, "Erro ao iniciar transação")
 	}
 	defer tx.Rollback(context.Background())
 
 	var invID int
 	upsertQuery := `
-		INSERT INTO board_invitations (board_id, inviter_id, invitee_id, status, created_at, updated_at)
-		VALUES ($1, $2, $3, 'pending', NOW(), NOW())
+		INSERT INTO board_invitations (board_id, inviter_id, invitee_id, status, role, created_at, updated_at)
+		VALUES ($1, $2, $3, 'pending', $4, NOW(), NOW())
 		ON CONFLICT (board_id, invitee_id) -- Agora o PostgreSQL entende esta linha
 		DO UPDATE SET 
 			status = 'pending', 
 			inviter_id = EXCLUDED.inviter_id, 
+			role = EXCLUDED.role,
 			updated_at = NOW()
 		RETURNING id
 	`
-	err = tx.QueryRow(context.Background(), upsertQuery, boardID, inviterID, payload.InviteeID).Scan(&invID)
+	err = tx.QueryRow(context.Background(), upsertQuery, boardID, inviterID, payload.InviteeID, payload.Role).Scan(&invID)
 	if err != nil {
 		log.Printf("Erro ao fazer upsert do convite: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar ou reativar o convite"})
+		return internalError(c, "Erro ao criar ou reativar o convite")
 	}
 
 	var boardTitle string
@@ -1717,7 +2057,7 @@ func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
 	}
 
 	if err := tx.Commit(context.Background()); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao confirmar convite"})
+		return internalError(c, "Erro ao confirmar convite")
 	}
 
 	return c.Status(201).JSON(fiber.Map{"status": "invited"})
@@ -1727,7 +2067,7 @@ func (app *App) inviteUserToBoard(c *fiber.Ctx) error {
 func (app *App) respondToInvitation(c *fiber.Ctx) error {
 	invitationID, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID de convite inválido"})
+		return badRequest(c, "ID de convite inválido")
 	}
 	notificationID_str := c.Query("notification_id")
 	notificationID, _ := strconv.Atoi(notificationID_str)
@@ -1738,42 +2078,43 @@ func (app *App) respondToInvitation(c *fiber.Ctx) error {
 		Accept bool `json:"accept"`
 	}
 	if err := c.BodyParser(&payload); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payload inválido"})
+		return badRequest(c, "Payload inválido")
 	}
 
 	tx, err := app.db.Begin(context.Background())
 	if err != nil {
 		log.Printf("[RESPOND_INVITE] Erro ao iniciar transação: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro interno do servidor"})
+		return internalError(c, "Erro interno do servidor")
 	}
 	defer tx.Rollback(context.Background())
 
 	var boardID int
+	var role string
 	status := "rejected"
 	if payload.Accept {
 		status = "accepted"
 	}
 
 	err = tx.QueryRow(context.Background(),
-		"UPDATE board_invitations SET status = $1, updated_at = now() WHERE id = $2 AND invitee_id = $3 AND status = 'pending' RETURNING board_id",
-		status, invitationID, userID).Scan(&boardID)
+		"UPDATE board_invitations SET status = $1, updated_at = now() WHERE id = $2 AND invitee_id = $3 AND status = 'pending' RETURNING board_id, role",
+		status, invitationID, userID).Scan(&boardID, &role)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
-			return c.Status(404).JSON(fiber.Map{"error": "Convite inválido, expirado ou já respondido."})
+			return notFound(c, "Convite inválido, expirado ou já respondido.")
 		}
 		log.Printf("[RESPOND_INVITE] Erro ao atualizar convite (passo 3): %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao processar resposta ao convite"})
+		return internalError(c, "Erro ao processar resposta ao convite")
 	}
 
 	if payload.Accept {
 		_, err = tx.Exec(context.Background(),
-			"INSERT INTO board_memberships (board_id, user_id) VALUES ($1, $2) ON CONFLICT (board_id, user_id) DO NOTHING",
-			boardID, userID)
+			"INSERT INTO board_memberships (board_id, user_id, role) VALUES ($1, $2, $3) ON CONFLICT (board_id, user_id) DO UPDATE SET role = EXCLUDED.role",
+			boardID, userID, role)
 
 		if err != nil {
 			log.Printf("[RESPOND_INVITE] Erro CRÍTICO ao inserir em board_memberships (passo 4): %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "Erro ao adicionar membro ao quadro"})
+			return internalError(c, "Erro ao adicionar membro ao quadro")
 		}
 
 		var ownerID string
@@ -1798,22 +2139,22 @@ func (app *App) respondToInvitation(c *fiber.Ctx) error {
 
 	if err := tx.Commit(context.Background()); err != nil {
 		log.Printf("[RESPOND_INVITE] Erro ao comitar a transação (passo 7): %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao finalizar a operação"})
+		return internalError(c, "Erro ao finalizar a operação")
 	}
 
 	return c.Status(200).JSON(fiber.Map{"status": "responded"})
 }
 
 func (app *App) getLigacoes(c *fiber.Ctx) error {
-	rows, err := app.db.Query(context.Background(), "SELECT id, name, type, image_url, status, spreadsheet_url, address, end_date, observations, created_at, updated_at FROM ligacoes ORDER BY name")
+	rows, err := app.db.Query(context.Background(), "SELECT "+ligacaoSelectColumns+" FROM ligacoes ORDER BY name")
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar ligações"})
+		return internalError(c, "Erro ao buscar ligações")
 	}
 	defer rows.Close()
 	ligacoes := make([]Ligacao, 0)
 	for rows.Next() {
 		var l Ligacao
-		if err := rows.Scan(&l.ID, &l.Name, &l.Type, &l.ImageURL, &l.Status, &l.SpreadsheetURL, &l.Address, &l.EndDate, &l.Observations, &l.CreatedAt, &l.UpdatedAt); err == nil {
+		if err := scanLigacao(rows, &l); err == nil {
 			ligacoes = append(ligacoes, l)
 		}
 	}
@@ -1823,13 +2164,16 @@ func (app *App) getLigacoes(c *fiber.Ctx) error {
 func (app *App) createLigacao(c *fiber.Ctx) error {
 	var ligacao Ligacao
 	if err := c.BodyParser(&ligacao); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateLigacao(&ligacao); err != nil {
+		return validationFailed(c, err)
 	}
-	query := `INSERT INTO ligacoes (name, type, status, spreadsheet_url, address, end_date, observations) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
-	err := app.db.QueryRow(context.Background(), query, ligacao.Name, ligacao.Type, ligacao.Status, ligacao.SpreadsheetURL, ligacao.Address, ligacao.EndDate, ligacao.Observations).Scan(&ligacao.ID, &ligacao.CreatedAt, &ligacao.UpdatedAt)
+	query := `INSERT INTO ligacoes (name, type, status, spreadsheet_url, address, end_date, observations) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at, version`
+	err := app.db.QueryRow(context.Background(), query, ligacao.Name, ligacao.Type, ligacao.Status, ligacao.SpreadsheetURL, ligacao.Address, ligacao.EndDate, ligacao.Observations).Scan(&ligacao.ID, &ligacao.CreatedAt, &ligacao.UpdatedAt, &ligacao.Version)
 	if err != nil {
 		log.Printf("Erro ao criar ligação no DB: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar ligação"})
+		return internalError(c, "Erro ao criar ligação")
 	}
 	return c.Status(201).JSON(ligacao)
 }
@@ -1838,15 +2182,31 @@ func (app *App) updateLigacao(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	var ligacao Ligacao
 	if err := c.BodyParser(&ligacao); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateLigacao(&ligacao); err != nil {
+		return validationFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, ligacao.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
 	}
-	query := `UPDATE ligacoes SET name=$1, type=$2, status=$3, spreadsheet_url=$4, address=$5, end_date=$6, observations=$7, updated_at=NOW() WHERE id=$8`
-	_, err := app.db.Exec(context.Background(), query, ligacao.Name, ligacao.Type, ligacao.Status, ligacao.SpreadsheetURL, ligacao.Address, ligacao.EndDate, ligacao.Observations, id)
+	query := `UPDATE ligacoes SET name=$1, type=$2, status=$3, spreadsheet_url=$4, address=$5, end_date=$6, observations=$7, updated_at=NOW(), version=version+1
+			  WHERE id=$8 AND ($9 = 0 OR version = $9)
+			  RETURNING ` + ligacaoSelectColumns
+	err = scanLigacao(app.db.QueryRow(context.Background(), query, ligacao.Name, ligacao.Type, ligacao.Status, ligacao.SpreadsheetURL, ligacao.Address, ligacao.EndDate, ligacao.Observations, id, expectedVersion), &ligacao)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conditionalUpdateMiss(c, func() (interface{}, int, error) {
+				var current Ligacao
+				err := scanLigacao(app.db.QueryRow(context.Background(), "SELECT "+ligacaoSelectColumns+" FROM ligacoes WHERE id=$1", id), &current)
+				return current, current.Version, err
+			}, "Ligação não encontrada")
+		}
 		log.Printf("Erro ao atualizar ligação no DB: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar ligação"})
+		return internalError(c, "Erro ao atualizar ligação")
 	}
-	ligacao.ID = id
+	setVersionETag(c, ligacao.Version)
 	return c.JSON(ligacao)
 }
 
@@ -1854,7 +2214,7 @@ func (app *App) deleteLigacao(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	_, err := app.db.Exec(context.Background(), "DELETE FROM ligacoes WHERE id=$1", id)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar ligação"})
+		return internalError(c, "Erro ao deletar ligação")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -1863,7 +2223,7 @@ func (app *App) handleLigacaoImageUpload(c *fiber.Ctx) error {
 	ligacaoID, _ := strconv.Atoi(c.Params("id"))
 	file, err := c.FormFile("image")
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Nenhum arquivo enviado"})
+		return badRequest(c, "Nenhum arquivo enviado")
 	}
 
 	src, _ := file.Open()
@@ -1883,40 +2243,36 @@ func (app *App) handleLigacaoImageUpload(c *fiber.Ctx) error {
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil || resp.StatusCode != http.StatusOK {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Falha ao armazenar o arquivo"})
+		return internalError(c, "Falha ao armazenar o arquivo")
 	}
 	defer resp.Body.Close()
 
 	publicURL := fmt.Sprintf("%s/storage/v1/object/public/ligacoes/%s", supabaseURL, fileName)
 	_, err = app.db.Exec(context.Background(), "UPDATE ligacoes SET image_url=$1 WHERE id=$2", publicURL, ligacaoID)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro ao atualizar URL da imagem no banco"})
+		return internalError(c, "Erro ao atualizar URL da imagem no banco")
 	}
 	return c.JSON(fiber.Map{"image_url": publicURL})
 }
 
 // pegar membros board
 func (app *App) getBoardMembers(c *fiber.Ctx) error {
-	boardID, _ := strconv.Atoi(c.Params("id"))
-	userID := c.Locals("userID").(string)
-	hasPermission, err := app.checkBoardPermission(userID, boardID)
-	if err != nil || !hasPermission {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Acesso negado."})
-	}
-	query := `(SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, true as is_owner FROM auth.users u JOIN boards b ON u.id = b.owner_id WHERE b.id = $1) UNION (SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, false as is_owner FROM auth.users u JOIN board_memberships bm ON u.id = bm.user_id WHERE bm.board_id = $1 AND u.id NOT IN (SELECT owner_id FROM boards WHERE id = $1)) ORDER BY is_owner DESC, username;`
+	boardID := authorizedBoardID(c)
+	query := `(SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, true as is_owner, 'owner' as role FROM auth.users u JOIN boards b ON u.id = b.owner_id WHERE b.id = $1) UNION (SELECT u.id, u.email, COALESCE(u.raw_user_meta_data->>'username', u.email) as username, COALESCE(u.raw_user_meta_data->>'avatar_url', '') as avatar, false as is_owner, bm.role FROM auth.users u JOIN board_memberships bm ON u.id = bm.user_id WHERE bm.board_id = $1 AND u.id NOT IN (SELECT owner_id FROM boards WHERE id = $1)) ORDER BY is_owner DESC, username;`
 	rows, err := app.db.Query(context.Background(), query, boardID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar membros"})
+		return internalError(c, "Erro ao buscar membros")
 	}
 	defer rows.Close()
 	type Member struct {
 		User
-		IsOwner bool `json:"is_owner"`
+		IsOwner bool      `json:"is_owner"`
+		Role    BoardRole `json:"role"`
 	}
 	members := make([]Member, 0)
 	for rows.Next() {
 		var member Member
-		if err := rows.Scan(&member.ID, &member.Email, &member.Username, &member.Avatar, &member.IsOwner); err == nil {
+		if err := rows.Scan(&member.ID, &member.Email, &member.Username, &member.Avatar, &member.IsOwner, &member.Role); err == nil {
 			members = append(members, member)
 		}
 	}
@@ -1940,7 +2296,7 @@ func (app *App) getNotifications(c *fiber.Ctx) error {
 	rows, err := app.db.Query(context.Background(), query, userID)
 	if err != nil {
 		log.Printf("Erro ao buscar notificações com join: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar notificações"})
+		return internalError(c, "Erro ao buscar notificações")
 	}
 	defer rows.Close()
 
@@ -1962,7 +2318,7 @@ func (app *App) markNotificationRead(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
 	_, err := app.db.Exec(context.Background(), "UPDATE notifications SET is_read = true WHERE id = $1 AND user_id = $2", notificationID, userID)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao marcar notificação como lida"})
+		return internalError(c, "Erro ao marcar notificação como lida")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -1970,7 +2326,7 @@ func (app *App) markNotificationRead(c *fiber.Ctx) error {
 func (app *App) markAllNotificationsRead(c *fiber.Ctx) error {
 	userID, ok := c.Locals("userID").(string)
 	if !ok || userID == "" {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "ID do usuário não pôde ser verificado"})
+		return unauthorized(c, "ID do usuário não pôde ser verificado")
 	}
 
 	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE AND type != 'board_invitation'`
@@ -1978,7 +2334,7 @@ func (app *App) markAllNotificationsRead(c *fiber.Ctx) error {
 	cmdTag, err := app.db.Exec(context.Background(), query, userID)
 	if err != nil {
 		log.Printf("❌ Erro ao marcar todas as notificações como lidas para o usuário %s: %v", userID, err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Erro interno ao atualizar as notificações"})
+		return internalError(c, "Erro interno ao atualizar as notificações")
 	}
 
 	log.Printf("Notificações marcadas como lidas para o usuário %s: %d", userID, cmdTag.RowsAffected())
@@ -1990,7 +2346,7 @@ func (app *App) markAllNotificationsRead(c *fiber.Ctx) error {
 func (app *App) removeBoardMember(c *fiber.Ctx) error {
 	boardID, err := strconv.Atoi(c.Params("boardId"))
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "ID do quadro inválido"})
+		return badRequest(c, "ID do quadro inválido")
 	}
 	memberIdToRemove := c.Params("memberId")
 	currentUserID := c.Locals("userID").(string)
@@ -1998,21 +2354,22 @@ func (app *App) removeBoardMember(c *fiber.Ctx) error {
 	var ownerID string
 	err = app.db.QueryRow(context.Background(), "SELECT owner_id FROM boards WHERE id = $1", boardID).Scan(&ownerID)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quadro não encontrado"})
+		return notFound(c, "Quadro não encontrado")
 	}
 
 	if ownerID != currentUserID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "Apenas o dono do quadro pode remover membros."})
+		return accessDenied(c, "Apenas o dono do quadro pode remover membros.")
 	}
 
 	if ownerID == memberIdToRemove {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "O dono do quadro não pode ser removido."})
+		return badRequest(c, "O dono do quadro não pode ser removido.")
 	}
 
 	_, err = app.db.Exec(context.Background(), "DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, memberIdToRemove)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Falha ao remover o membro do banco de dados."})
+		return internalError(c, "Falha ao remover o membro do banco de dados.")
 	}
+	app.disconnectBoardClients(boardID, memberIdToRemove)
 
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2023,7 +2380,7 @@ func (app *App) getAgendaEvents(c *fiber.Ctx) error {
 	year, _ := strconv.Atoi(c.Query("year"))
 
 	if month == 0 || year == 0 {
-		return c.Status(400).JSON(fiber.Map{"error": "Mês e ano são obrigatórios"})
+		return badRequest(c, "Mês e ano são obrigatórios")
 	}
 
 	startDate := fmt.Sprintf("%d-%02d-01", year, month)
@@ -2032,17 +2389,17 @@ func (app *App) getAgendaEvents(c *fiber.Ctx) error {
 		endDate = fmt.Sprintf("%d-01-01", year+1)
 	}
 
-	query := "SELECT id, title, description, event_date, color, user_id, created_at, updated_at FROM agenda_events WHERE event_date >= $1 AND event_date < $2"
+	query := "SELECT " + agendaEventSelectColumns + " FROM agenda_events WHERE event_date >= $1 AND event_date < $2"
 	rows, err := app.db.Query(context.Background(), query, startDate, endDate)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar eventos da agenda"})
+		return internalError(c, "Erro ao buscar eventos da agenda")
 	}
 	defer rows.Close()
 
 	events := make([]AgendaEvent, 0)
 	for rows.Next() {
 		var e AgendaEvent
-		if err := rows.Scan(&e.ID, &e.Title, &e.Description, &e.EventDate, &e.Color, &e.UserID, &e.CreatedAt, &e.UpdatedAt); err == nil {
+		if err := scanAgendaEvent(rows, &e); err == nil {
 			events = append(events, e)
 		}
 	}
@@ -2053,15 +2410,18 @@ func (app *App) getAgendaEvents(c *fiber.Ctx) error {
 func (app *App) createAgendaEvent(c *fiber.Ctx) error {
 	var event AgendaEvent
 	if err := c.BodyParser(&event); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateAgendaEvent(&event); err != nil {
+		return validationFailed(c, err)
 	}
 	userID := c.Locals("userID").(string)
 	event.UserID = &userID
 
-	query := `INSERT INTO agenda_events (title, description, event_date, color, user_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
-	err := app.db.QueryRow(context.Background(), query, event.Title, event.Description, event.EventDate, event.Color, event.UserID).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt)
+	query := `INSERT INTO agenda_events (title, description, event_date, color, user_id) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at, version`
+	err := app.db.QueryRow(context.Background(), query, event.Title, event.Description, event.EventDate, event.Color, event.UserID).Scan(&event.ID, &event.CreatedAt, &event.UpdatedAt, &event.Version)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar evento"})
+		return internalError(c, "Erro ao criar evento")
 	}
 	return c.Status(201).JSON(event)
 }
@@ -2070,21 +2430,38 @@ func (app *App) createAgendaEvent(c *fiber.Ctx) error {
 func (app *App) updateAgendaEvent(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "ID de evento inválido"})
+		return badRequest(c, "ID de evento inválido")
 	}
 
 	var event AgendaEvent
 	if err := c.BodyParser(&event); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados de evento inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateAgendaEvent(&event); err != nil {
+		return validationFailed(c, err)
+	}
+
+	expectedVersion, err := requestedVersion(c, event.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
 	}
 
-	query := `UPDATE agenda_events SET title=$1, description=$2, event_date=$3, color=$4, updated_at=NOW() WHERE id=$5`
-	_, err = app.db.Exec(context.Background(), query, event.Title, event.Description, event.EventDate, event.Color, id)
+	query := `UPDATE agenda_events SET title=$1, description=$2, event_date=$3, color=$4, updated_at=NOW(), version=version+1
+			  WHERE id=$5 AND ($6 = 0 OR version = $6)
+			  RETURNING ` + agendaEventSelectColumns
+	err = scanAgendaEvent(app.db.QueryRow(context.Background(), query, event.Title, event.Description, event.EventDate, event.Color, id, expectedVersion), &event)
 	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conditionalUpdateMiss(c, func() (interface{}, int, error) {
+				var current AgendaEvent
+				err := scanAgendaEvent(app.db.QueryRow(context.Background(), "SELECT "+agendaEventSelectColumns+" FROM agenda_events WHERE id=$1", id), &current)
+				return current, current.Version, err
+			}, "Evento não encontrado")
+		}
 		log.Printf("Erro ao atualizar evento: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar evento no banco de dados"})
+		return internalError(c, "Erro ao atualizar evento no banco de dados")
 	}
-	event.ID = id
+	setVersionETag(c, event.Version)
 	return c.JSON(event)
 }
 
@@ -2092,29 +2469,29 @@ func (app *App) updateAgendaEvent(c *fiber.Ctx) error {
 func (app *App) deleteAgendaEvent(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "ID de evento inválido"})
+		return badRequest(c, "ID de evento inválido")
 	}
 
 	_, err = app.db.Exec(context.Background(), "DELETE FROM agenda_events WHERE id=$1", id)
 	if err != nil {
 		log.Printf("Erro ao deletar evento: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar evento"})
+		return internalError(c, "Erro ao deletar evento")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
 // endpoint  avaliacao
 func (app *App) getAvaliacoes(c *fiber.Ctx) error {
-	query := `SELECT id, source, customer_name, review_content, rating, status, review_date, review_url, assigned_to, resolution_notes, created_at, updated_at FROM avaliacoes ORDER BY review_date DESC`
+	query := `SELECT ` + avaliacaoSelectColumns + ` FROM avaliacoes ORDER BY review_date DESC`
 	rows, err := app.db.Query(context.Background(), query)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao buscar avaliações"})
+		return internalError(c, "Erro ao buscar avaliações")
 	}
 	defer rows.Close()
 	avaliacoes := make([]Avaliacao, 0)
 	for rows.Next() {
 		var a Avaliacao
-		if err := rows.Scan(&a.ID, &a.Source, &a.CustomerName, &a.ReviewContent, &a.Rating, &a.Status, &a.ReviewDate, &a.ReviewURL, &a.AssignedTo, &a.ResolutionNotes, &a.CreatedAt, &a.UpdatedAt); err == nil {
+		if err := scanAvaliacao(rows, &a); err == nil {
 			avaliacoes = append(avaliacoes, a)
 		}
 	}
@@ -2126,15 +2503,18 @@ func (app *App) createAvaliacao(c *fiber.Ctx) error {
 	var avaliacao Avaliacao
 	if err := c.BodyParser(&avaliacao); err != nil {
 		log.Printf("❌ Erro ao fazer parse do corpo da requisição para Avaliacao: %v", err)
-		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateAvaliacao(&avaliacao); err != nil {
+		return validationFailed(c, err)
 	}
 
-	query := `INSERT INTO avaliacoes (source, customer_name, review_content, rating, status, review_date, review_url, assigned_to, resolution_notes) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at`
+	query := `INSERT INTO avaliacoes (source, customer_name, review_content, rating, status, review_date, review_url, assigned_to, resolution_notes) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at, updated_at, version`
 
-	err := app.db.QueryRow(context.Background(), query, avaliacao.Source, avaliacao.CustomerName, avaliacao.ReviewContent, avaliacao.Rating, avaliacao.Status, avaliacao.ReviewDate, avaliacao.ReviewURL, avaliacao.AssignedTo, avaliacao.ResolutionNotes).Scan(&avaliacao.ID, &avaliacao.CreatedAt, &avaliacao.UpdatedAt)
+	err := app.db.QueryRow(context.Background(), query, avaliacao.Source, avaliacao.CustomerName, avaliacao.ReviewContent, avaliacao.Rating, avaliacao.Status, avaliacao.ReviewDate, avaliacao.ReviewURL, avaliacao.AssignedTo, avaliacao.ResolutionNotes).Scan(&avaliacao.ID, &avaliacao.CreatedAt, &avaliacao.UpdatedAt, &avaliacao.Version)
 
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao criar avaliação"})
+		return internalError(c, "Erro ao criar avaliação")
 	}
 
 	return c.Status(201).JSON(avaliacao)
@@ -2145,14 +2525,30 @@ func (app *App) updateAvaliacao(c *fiber.Ctx) error {
 	id, _ := strconv.Atoi(c.Params("id"))
 	var avaliacao Avaliacao
 	if err := c.BodyParser(&avaliacao); err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "Dados inválidos"})
+		return invalidBody(c)
+	}
+	if err := validateAvaliacao(&avaliacao); err != nil {
+		return validationFailed(c, err)
 	}
-	query := `UPDATE avaliacoes SET source=$1, customer_name=$2, review_content=$3, rating=$4, status=$5, review_date=$6, review_url=$7, assigned_to=$8, resolution_notes=$9, updated_at=NOW() WHERE id=$10`
-	_, err := app.db.Exec(context.Background(), query, avaliacao.Source, avaliacao.CustomerName, avaliacao.ReviewContent, avaliacao.Rating, avaliacao.Status, avaliacao.ReviewDate, avaliacao.ReviewURL, avaliacao.AssignedTo, avaliacao.ResolutionNotes, id)
+	expectedVersion, err := requestedVersion(c, avaliacao.Version)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao atualizar avaliação"})
+		return invalidPrecondition(c, err)
 	}
-	avaliacao.ID = id
+	query := `UPDATE avaliacoes SET source=$1, customer_name=$2, review_content=$3, rating=$4, status=$5, review_date=$6, review_url=$7, assigned_to=$8, resolution_notes=$9, updated_at=NOW(), version=version+1
+			  WHERE id=$10 AND ($11 = 0 OR version = $11)
+			  RETURNING ` + avaliacaoSelectColumns
+	err = scanAvaliacao(app.db.QueryRow(context.Background(), query, avaliacao.Source, avaliacao.CustomerName, avaliacao.ReviewContent, avaliacao.Rating, avaliacao.Status, avaliacao.ReviewDate, avaliacao.ReviewURL, avaliacao.AssignedTo, avaliacao.ResolutionNotes, id, expectedVersion), &avaliacao)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conditionalUpdateMiss(c, func() (interface{}, int, error) {
+				var current Avaliacao
+				err := scanAvaliacao(app.db.QueryRow(context.Background(), "SELECT "+avaliacaoSelectColumns+" FROM avaliacoes WHERE id=$1", id), &current)
+				return current, current.Version, err
+			}, "Avaliação não encontrada")
+		}
+		return internalError(c, "Erro ao atualizar avaliação")
+	}
+	setVersionETag(c, avaliacao.Version)
 	return c.JSON(avaliacao)
 }
 
@@ -2160,13 +2556,13 @@ func (app *App) updateAvaliacao(c *fiber.Ctx) error {
 func (app *App) deleteAvaliacao(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": "ID de avaliação inválido"})
+		return badRequest(c, "ID de avaliação inválido")
 	}
 
 	_, err = app.db.Exec(context.Background(), "DELETE FROM avaliacoes WHERE id=$1", id)
 	if err != nil {
 		log.Printf("Erro ao deletar avaliação: %v", err)
-		return c.Status(500).JSON(fiber.Map{"error": "Erro ao deletar avaliação"})
+		return internalError(c, "Erro ao deletar avaliação")
 	}
 	return c.SendStatus(fiber.StatusNoContent)
 }
@@ -2187,23 +2583,44 @@ func main() {
 		log.Println("Arquivo .env não encontrado, usando variáveis de ambiente do sistema.")
 	}
 
-	app := &App{clients: make(map[int]map[*websocket.Conn]bool)}
+	app := &App{hub: NewHub(), presence: NewPresenceRegistry()}
+	go app.hub.Run()
 
 	if err := app.connectDB(); err != nil {
 		log.Fatalf("Falha ao conectar ao banco de dados: %v", err)
 	}
 	defer app.db.Close()
 
+	if err := app.runMigrations(); err != nil {
+		log.Fatalf("Falha ao aplicar migrações: %v", err)
+	}
+
+	if err := app.setupEventBus(); err != nil {
+		log.Fatalf("Falha ao configurar o barramento de eventos: %v", err)
+	}
+	defer app.bus.Close()
+
+	presenceCtx, stopPresence := context.WithCancel(context.Background())
+	defer stopPresence()
+	go app.runPresenceHeartbeat(presenceCtx)
+
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go app.runTrashPurge(purgeCtx)
+
 	fiberApp := fiber.New()
 	fiberApp.Use(logger.New(), recover.New())
 	fiberApp.Use(cors.New(cors.Config{
 		AllowOrigins:     "http://localhost:10001, http://127.0.0.1:10001",
 		AllowCredentials: true,
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
+		AllowMethods:     "GET,POST,PUT,PATCH,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,If-Match,If-None-Match",
+		ExposeHeaders:    "ETag",
 	}))
 	app.setupRoutes(fiberApp)
-	fiberApp.Get("/ws/board/:id", websocket.New(app.handleWebSocket))
+	fiberApp.Get("/ws/board/:id", app.wsAuthMiddleware, websocket.New(app.handleWebSocket, websocket.Config{
+		Subprotocols: []string{wsAuthSubprotocol},
+	}))
 
 	fiberApp.Static("/", "./react-frontend/dist")
 
@@ -2220,6 +2637,21 @@ func main() {
 		log.Fatalf("ERRO: A porta %s já está em uso.", port)
 	}
 
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+		<-quit
+		log.Println("Encerrando servidor...")
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := app.hub.Shutdown(ctx); err != nil {
+			log.Printf("Erro ao encerrar hub de websockets: %v", err)
+		}
+		if err := fiberApp.ShutdownWithContext(ctx); err != nil {
+			log.Printf("Erro ao encerrar servidor: %v", err)
+		}
+	}()
+
 	addr := fmt.Sprintf("0.0.0.0:%s", port)
 	log.Printf("Servidor iniciando na porta %s", port)
 	if err := fiberApp.Listen(addr); err != nil {
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// ddl idempotente executado na inicialização
+var schemaStatements = []string{
+	`CREATE TABLE IF NOT EXISTS app_migrations (
+		name       TEXT PRIMARY KEY,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS card_comments (
+		id          SERIAL PRIMARY KEY,
+		card_id     INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+		author_id   UUID,
+		author_name TEXT NOT NULL DEFAULT '',
+		section     TEXT,
+		content     TEXT NOT NULL,
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_comments_card_id ON card_comments (card_id, created_at)`,
+	// sem FK em card_id: o histórico sobrevive à exclusão do card
+	`CREATE TABLE IF NOT EXISTS card_events (
+		id         BIGSERIAL PRIMARY KEY,
+		card_id    INTEGER NOT NULL,
+		board_id   INTEGER NOT NULL,
+		actor_id   UUID,
+		event_type TEXT NOT NULL,
+		field      TEXT,
+		old_value  JSONB,
+		new_value  JSONB,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_events_card_id ON card_events (card_id, id DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_events_board_id ON card_events (board_id, id DESC)`,
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE columns ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE ligacoes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE avaliacoes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE agenda_events ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE boards ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE boards ADD COLUMN IF NOT EXISTS team TEXT`,
+	`ALTER TABLE boards ADD COLUMN IF NOT EXISTS is_default BOOLEAN NOT NULL DEFAULT false`,
+	`CREATE TABLE IF NOT EXISTS board_sequences (
+		board_id INTEGER PRIMARY KEY,
+		last_seq BIGINT NOT NULL DEFAULT 0
+	)`,
+	`CREATE TABLE IF NOT EXISTS board_event_log (
+		board_id   INTEGER NOT NULL,
+		seq        BIGINT NOT NULL,
+		type       TEXT NOT NULL,
+		sender_id  TEXT,
+		payload    JSONB,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (board_id, seq)
+	)`,
+	// lixeira: itens com deleted_at ficam ocultos até o purge
+	`ALTER TABLE boards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+	`ALTER TABLE boards ADD COLUMN IF NOT EXISTS deleted_by UUID`,
+	`ALTER TABLE columns ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+	`ALTER TABLE columns ADD COLUMN IF NOT EXISTS deleted_by UUID`,
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ`,
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS deleted_by UUID`,
+	`CREATE INDEX IF NOT EXISTS idx_boards_deleted_at ON boards (deleted_at) WHERE deleted_at IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_columns_deleted_at ON columns (deleted_at) WHERE deleted_at IS NOT NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_cards_deleted_at ON cards (deleted_at) WHERE deleted_at IS NOT NULL`,
+	// arquivamento é independente da conclusão (completed_at)
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ`,
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS archived_by UUID`,
+	`CREATE INDEX IF NOT EXISTS idx_cards_column_active ON cards (column_id, position) WHERE archived_at IS NULL AND deleted_at IS NULL`,
+	// busca textual em português no título e nos comentários
+	`ALTER TABLE cards ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (to_tsvector('portuguese', COALESCE(title, ''))) STORED`,
+	`CREATE INDEX IF NOT EXISTS idx_cards_search ON cards USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_comments_search ON card_comments USING GIN (to_tsvector('portuguese', content))`,
+	// colunas (com cards iniciais) e etiquetas em jsonb; owner_id nulo = template do sistema
+	`CREATE TABLE IF NOT EXISTS board_templates (
+		id          SERIAL PRIMARY KEY,
+		name        TEXT NOT NULL,
+		description TEXT,
+		color       TEXT,
+		owner_id    UUID,
+		is_shared   BOOLEAN NOT NULL DEFAULT false,
+		columns     JSONB NOT NULL DEFAULT '[]',
+		labels      JSONB NOT NULL DEFAULT '[]',
+		created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	// filtros de busca salvos; board_id define com quem a visão é compartilhada
+	`CREATE TABLE IF NOT EXISTS saved_views (
+		id         SERIAL PRIMARY KEY,
+		name       TEXT NOT NULL,
+		owner_id   UUID NOT NULL,
+		board_id   INTEGER REFERENCES boards(id) ON DELETE CASCADE,
+		is_shared  BOOLEAN NOT NULL DEFAULT false,
+		filters    JSONB NOT NULL DEFAULT '{}',
+		group_by   TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_saved_views_owner ON saved_views (owner_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_saved_views_board ON saved_views (board_id) WHERE is_shared`,
+	// etiquetas por board; o nome é único no board sem diferenciar maiúsculas
+	`CREATE TABLE IF NOT EXISTS labels (
+		id         SERIAL PRIMARY KEY,
+		board_id   INTEGER NOT NULL REFERENCES boards(id) ON DELETE CASCADE,
+		name       TEXT NOT NULL,
+		color      TEXT NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	)`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_labels_board_name ON labels (board_id, lower(name))`,
+	`CREATE TABLE IF NOT EXISTS card_labels (
+		card_id    INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+		label_id   INTEGER NOT NULL REFERENCES labels(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (card_id, label_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_labels_label ON card_labels (label_id)`,
+	// responsáveis pelo id do usuário; assigned_to fica só como nome do primeiro deles
+	`CREATE TABLE IF NOT EXISTS card_assignees (
+		card_id    INTEGER NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+		user_id    UUID NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (card_id, user_id)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_card_assignees_user ON card_assignees (user_id)`,
+	// membros existentes mantêm o acesso de edição que já tinham
+	`ALTER TABLE board_memberships ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor'
+		CHECK (role IN ('editor', 'commenter', 'viewer'))`,
+	`ALTER TABLE board_invitations ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'editor'
+		CHECK (role IN ('editor', 'commenter', 'viewer'))`,
+}
+
+// migrações de dados que rodam uma única vez
+var dataMigrations = []struct {
+	Name string
+	Run  func(app *App, ctx context.Context) error
+}{
+	{"card_comments_from_description", (*App).migrateDescriptionComments},
+	{"default_public_board", (*App).migrateDefaultPublicBoard},
+	{"default_board_template", (*App).migrateDefaultBoardTemplate},
+	{"card_assignees_from_assigned_to", (*App).migrateCardAssignees},
+	{"public_board_status_columns", (*App).migratePublicBoardStatusColumns},
+}
+
+// aplicar schema e migrações
+func (app *App) runMigrations() error {
+	ctx := context.Background()
+	for _, stmt := range schemaStatements {
+		if _, err := app.db.Exec(ctx, stmt); err != nil {
+			return fmt.Errorf("erro ao aplicar schema: %w", err)
+		}
+	}
+	for _, m := range dataMigrations {
+		var applied bool
+		err := app.db.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM app_migrations WHERE name = $1)", m.Name).Scan(&applied)
+		if err != nil {
+			return fmt.Errorf("erro ao verificar migração %s: %w", m.Name, err)
+		}
+		if applied {
+			continue
+		}
+		if err := m.Run(app, ctx); err != nil {
+			return fmt.Errorf("erro na migração %s: %w", m.Name, err)
+		}
+		if _, err := app.db.Exec(ctx, "INSERT INTO app_migrations (name) VALUES ($1)", m.Name); err != nil {
+			return fmt.Errorf("erro ao registrar migração %s: %w", m.Name, err)
+		}
+		log.Printf("Migração aplicada: %s", m.Name)
+	}
+	return nil
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// campo aceito num PATCH; o nome JSON é o mesmo da coluna
+type patchField struct {
+	Nullable bool
+	Decode   func(json.RawMessage) (interface{}, error)
+}
+
+// patch já validado
+type mergePatch struct {
+	Raw     map[string]json.RawMessage
+	Values  map[string]interface{}
+	Version int
+}
+
+var errPatchNotObject = errors.New("o corpo deve ser um objeto JSON")
+
+// ler corpo no formato JSON Merge Patch (RFC 7396): ausente = mantém, null = limpa
+func decodeMergePatch(body []byte, fields map[string]patchField) (mergePatch, error) {
+	patch := mergePatch{Raw: make(map[string]json.RawMessage), Values: make(map[string]interface{})}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(body, &raw); err != nil || raw == nil {
+		return patch, errPatchNotObject
+	}
+	var v Validator
+	if value, ok := raw["version"]; ok {
+		if err := json.Unmarshal(value, &patch.Version); err != nil {
+			v.Add("version", "invalid_type", "deve ser um número inteiro")
+		}
+		delete(raw, "version")
+	}
+	for key, value := range raw {
+		field, ok := fields[key]
+		if !ok {
+			v.Add(key, "not_editable", "campo não editável")
+			continue
+		}
+		if isJSONNull(value) {
+			if !field.Nullable {
+				v.Add(key, "required", "não pode ser nulo")
+				continue
+			}
+			patch.Values[key] = nil
+		} else {
+			decoded, err := field.Decode(value)
+			if err != nil {
+				v.Add(key, "invalid_type", err.Error())
+				continue
+			}
+			patch.Values[key] = decoded
+		}
+		patch.Raw[key] = value
+	}
+	if err := v.Err(); err != nil {
+		return patch, err
+	}
+	if len(patch.Values) == 0 {
+		return patch, errors.New("nenhum campo para atualizar")
+	}
+	return patch, nil
+}
+
+// resposta para erro de decodeMergePatch
+func patchDecodeFailed(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errPatchNotObject) {
+		return invalidBody(c)
+	}
+	return validationFailed(c, err)
+}
+
+func isJSONNull(v json.RawMessage) bool {
+	return bytes.Equal(bytes.TrimSpace(v), []byte("null"))
+}
+
+// aplicar o patch sobre a representação JSON de src e devolver em dst
+func applyMergePatch(src, dst interface{}, patch mergePatch) error {
+	encoded, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &doc); err != nil {
+		return err
+	}
+	for key, value := range patch.Raw {
+		if isJSONNull(value) {
+			delete(doc, key)
+		} else {
+			doc[key] = value
+		}
+	}
+	encoded, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, dst)
+}
+
+// campos do patch cujo valor realmente mudou
+func changedFields(before, after interface{}, patch mergePatch) (map[string]json.RawMessage, error) {
+	var beforeDoc, afterDoc map[string]json.RawMessage
+	for _, pair := range []struct {
+		v   interface{}
+		doc *map[string]json.RawMessage
+	}{{before, &beforeDoc}, {after, &afterDoc}} {
+		encoded, err := json.Marshal(pair.v)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(encoded, pair.doc); err != nil {
+			return nil, err
+		}
+	}
+	changes := make(map[string]json.RawMessage)
+	for key := range patch.Values {
+		afterValue, ok := afterDoc[key]
+		if !ok {
+			afterValue = json.RawMessage("null")
+		}
+		beforeValue, ok := beforeDoc[key]
+		if !ok {
+			beforeValue = json.RawMessage("null")
+		}
+		if !bytes.Equal(beforeValue, afterValue) {
+			changes[key] = afterValue
+		}
+	}
+	return changes, nil
+}
+
+// montar "col = $n" para os campos alterados; retorna o próximo índice livre
+func buildPatchSet(changes map[string]json.RawMessage, patch mergePatch) (string, []interface{}, int) {
+	keys := make([]string, 0, len(changes))
+	for key := range changes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	sets := make([]string, 0, len(keys))
+	args := make([]interface{}, 0, len(keys))
+	for i, key := range keys {
+		sets = append(sets, fmt.Sprintf("%s = $%d", key, i+1))
+		args = append(args, patch.Values[key])
+	}
+	return strings.Join(sets, ", "), args, len(keys) + 1
+}
+
+// decodificadores de tipo; as regras de cada campo ficam nos validate* do recurso
+func patchString(raw json.RawMessage) (interface{}, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, errors.New("deve ser um texto")
+	}
+	return s, nil
+}
+
+func patchStrings(raw json.RawMessage) (interface{}, error) {
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return nil, errors.New("deve ser uma lista de textos")
+	}
+	return values, nil
+}
+
+func patchTime(raw json.RawMessage) (interface{}, error) {
+	var t time.Time
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return nil, errors.New("deve ser uma data no formato RFC 3339")
+	}
+	return t, nil
+}
+
+func patchBool(raw json.RawMessage) (interface{}, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err != nil {
+		return nil, errors.New("deve ser true ou false")
+	}
+	return b, nil
+}
+
+func patchInt(raw json.RawMessage) (interface{}, error) {
+	var n int
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return nil, errors.New("deve ser um número inteiro")
+	}
+	return n, nil
+}
+
+var cardPatchFields = map[string]patchField{
+	"title":        {Decode: patchString},
+	"description":  {Nullable: true, Decode: patchString},
+	"assigned_to":  {Nullable: true, Decode: patchString},
+	"assignee_ids": {Nullable: true, Decode: patchStrings},
+	"priority":     {Decode: patchString},
+	"due_date":     {Nullable: true, Decode: patchTime},
+	"completed_at": {Nullable: true, Decode: patchTime},
+}
+
+var ligacaoPatchFields = map[string]patchField{
+	"name":            {Decode: patchString},
+	"type":            {Decode: patchString},
+	"status":          {Decode: patchString},
+	"spreadsheet_url": {Nullable: true, Decode: patchString},
+	"address":         {Nullable: true, Decode: patchString},
+	"end_date":        {Nullable: true, Decode: patchTime},
+	"observations":    {Nullable: true, Decode: patchString},
+}
+
+var avaliacaoPatchFields = map[string]patchField{
+	"source":           {Decode: patchString},
+	"customer_name":    {Decode: patchString},
+	"review_content":   {Decode: patchString},
+	"rating":           {Nullable: true, Decode: patchInt},
+	"status":           {Decode: patchString},
+	"review_date":      {Decode: patchTime},
+	"review_url":       {Nullable: true, Decode: patchString},
+	"assigned_to":      {Nullable: true, Decode: patchString},
+	"resolution_notes": {Nullable: true, Decode: patchString},
+}
+
+var agendaEventPatchFields = map[string]patchField{
+	"title":       {Decode: patchString},
+	"description": {Nullable: true, Decode: patchString},
+	"event_date":  {Decode: patchTime},
+	"color":       {Decode: patchString},
+}
+
+// endpoint patch card
+func (app *App) patchCard(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do card inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	patch, err := decodeMergePatch(c.Body(), cardPatchFields)
+	if err != nil {
+		return patchDecodeFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, patch.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(context.Background())
+
+	var boardID int
+	err = tx.QueryRow(context.Background(),
+		"SELECT col.board_id FROM cards ca JOIN columns col ON ca.column_id = col.id WHERE ca.id = $1 FOR UPDATE OF ca", cardID).Scan(&boardID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Tarefa não encontrada")
+		}
+		return internalError(c, "Erro ao buscar tarefa original")
+	}
+	existingCard, err := app.fetchCard(tx, cardID)
+	if err != nil {
+		return internalError(c, "Erro ao buscar tarefa original")
+	}
+	if expectedVersion != 0 && expectedVersion != existingCard.Version {
+		return versionConflict(c, existingCard.Version, existingCard)
+	}
+
+	// clientes antigos ainda gravam os comentários na descrição
+	if description, ok := patch.Values["description"].(string); ok {
+		stripped, err := app.absorbLegacyDescription(context.Background(), tx, cardID, description)
+		if err != nil {
+			log.Printf("Erro ao gravar comentários do card %d: %v", cardID, err)
+			return internalError(c, "Erro ao gravar comentários")
+		}
+		patch.Values["description"] = stripped
+		patch.Raw["description"], _ = json.Marshal(stripped)
+	}
+
+	// responsáveis não são colunas de cards: saem do patch e vão para card_assignees;
+	// assigned_to (clientes antigos) só conta quando muda e não veio assignee_ids
+	var requestedIDs []string
+	var legacyAssignedTo string
+	assigneesRequested := false
+	if value, ok := patch.Values["assignee_ids"]; ok {
+		ids, _ := value.([]string)
+		requestedIDs, assigneesRequested = nonNilStrings(ids), true
+	} else if value, ok := patch.Values["assigned_to"]; ok {
+		legacyAssignedTo, _ = value.(string)
+		assigneesRequested = legacyAssignedTo != existingCard.AssignedTo
+	}
+	for _, key := range []string{"assignee_ids", "assigned_to"} {
+		delete(patch.Values, key)
+		delete(patch.Raw, key)
+	}
+
+	var patched Card
+	if err := applyMergePatch(existingCard, &patched, patch); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateCard(&patched); err != nil {
+		return validationFailed(c, err)
+	}
+	changes, err := changedFields(existingCard, patched, patch)
+	if err != nil {
+		return internalError(c, "Erro ao aplicar patch")
+	}
+
+	var assignees []UserSummary
+	var addedAssignees []string
+	assigneesChanged := false
+	if assigneesRequested {
+		userIDs, problems, err := app.requestedAssignees(context.Background(), tx, boardID, requestedIDs, legacyAssignedTo)
+		if err != nil {
+			return internalError(c, "Erro ao validar responsáveis")
+		}
+		if len(problems) > 0 {
+			return validationFailed(c, &ValidationError{Fields: problems})
+		}
+		assignees, addedAssignees, assigneesChanged, err = app.updateCardAssignees(context.Background(), tx, cardID, boardID, userID, userIDs)
+		if err != nil {
+			log.Printf("Erro ao atribuir responsáveis do card %d: %v", cardID, err)
+			return internalError(c, "Erro ao atribuir responsáveis")
+		}
+	}
+
+	if len(changes) == 0 && !assigneesChanged {
+		setVersionETag(c, existingCard.Version)
+		return c.JSON(existingCard)
+	}
+
+	sets, args, next := buildPatchSet(changes, patch)
+	if assigneesChanged {
+		if sets != "" {
+			sets += ", "
+		}
+		sets += "assigned_to = " + assignedToFromAssignees
+	}
+	query := fmt.Sprintf(`UPDATE cards SET %s, updated_at = NOW(), version = version + 1 WHERE id = $%d RETURNING %s`,
+		sets, next, cardSelectColumns)
+	var updatedCard Card
+	if err := scanCard(tx.QueryRow(context.Background(), query, append(args, cardID)...), &updatedCard); err != nil {
+		log.Printf("Erro ao aplicar patch no card %d: %v", cardID, err)
+		return internalError(c, "Erro ao atualizar card no banco de dados")
+	}
+
+	if err := app.recordCardChanges(tx, boardID, userID, existingCard, updatedCard); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
+	}
+
+	if assigneesChanged {
+		updatedCard.Assignees = assignees
+		// os outros clientes recebem a lista nova junto com o patch
+		if raw, err := json.Marshal(assignees); err == nil {
+			changes["assignees"] = raw
+		}
+		if raw, err := json.Marshal(updatedCard.AssignedTo); err == nil {
+			changes["assigned_to"] = raw
+		}
+		app.notifyNewAssignees(context.Background(), tx, addedAssignees, boardID, cardID, updatedCard.Title)
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return internalError(c, "Erro ao confirmar atualização")
+	}
+
+	// só os campos alterados vão para os outros clientes
+	app.broadcast(boardID, WsMessage{
+		Type:     "CARD_PATCHED",
+		SenderID: userID,
+		Payload: fiber.Map{
+			"id":         updatedCard.ID,
+			"column_id":  updatedCard.ColumnID,
+			"version":    updatedCard.Version,
+			"updated_at": updatedCard.UpdatedAt,
+			"changes":    changes,
+		},
+	})
+
+	setVersionETag(c, updatedCard.Version)
+	return c.JSON(updatedCard)
+}
+
+// recurso simples (sem board) editável via PATCH
+type patchTarget[T any] struct {
+	Table         string
+	SelectColumns string
+	Fields        map[string]patchField
+	Scan          func(pgx.Row, *T) error
+	Validate      func(*T) error
+	Version       func(*T) int
+	NotFound      string
+	// opcional, roda na transação do UPDATE; erro desfaz a alteração
+	InTx func(context.Context, pgx.Tx, *T) error
+	// opcional, chamado após o commit (ex.: broadcast)
+	OnUpdated func(*fiber.Ctx, *T)
+}
+
+// PATCH genérico com pré-condição de versão
+func patchRecord[T any](app *App, c *fiber.Ctx, target patchTarget[T]) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID inválido")
+	}
+	patch, err := decodeMergePatch(c.Body(), target.Fields)
+	if err != nil {
+		return patchDecodeFailed(c, err)
+	}
+	expectedVersion, err := requestedVersion(c, patch.Version)
+	if err != nil {
+		return invalidPrecondition(c, err)
+	}
+
+	fetch := func() (interface{}, int, error) {
+		var current T
+		err := target.Scan(app.db.QueryRow(context.Background(),
+			"SELECT "+target.SelectColumns+" FROM "+target.Table+" WHERE id = $1", id), &current)
+		return current, target.Version(&current), err
+	}
+	var existing T
+	if err := target.Scan(app.db.QueryRow(context.Background(),
+		"SELECT "+target.SelectColumns+" FROM "+target.Table+" WHERE id = $1", id), &existing); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, target.NotFound)
+		}
+		return internalError(c, "Erro ao buscar o registro atual")
+	}
+	if expectedVersion != 0 && expectedVersion != target.Version(&existing) {
+		return versionConflict(c, target.Version(&existing), existing)
+	}
+
+	var patched T
+	if err := applyMergePatch(existing, &patched, patch); err != nil {
+		return invalidBody(c)
+	}
+	if err := target.Validate(&patched); err != nil {
+		return validationFailed(c, err)
+	}
+	changes, err := changedFields(existing, patched, patch)
+	if err != nil {
+		return internalError(c, "Erro ao aplicar patch")
+	}
+	if len(changes) == 0 {
+		setVersionETag(c, target.Version(&existing))
+		return c.JSON(existing)
+	}
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	// a versão lida acima protege contra escrita concorrente entre o SELECT e o UPDATE
+	sets, args, next := buildPatchSet(changes, patch)
+	query := fmt.Sprintf(`UPDATE %s SET %s, updated_at = NOW(), version = version + 1 WHERE id = $%d AND version = $%d RETURNING %s`,
+		target.Table, sets, next, next+1, target.SelectColumns)
+	var updated T
+	err = target.Scan(tx.QueryRow(ctx, query, append(args, id, target.Version(&existing))...), &updated)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return conditionalUpdateMiss(c, fetch, target.NotFound)
+		}
+		log.Printf("Erro ao aplicar patch em %s %d: %v", target.Table, id, err)
+		return internalError(c, "Erro ao atualizar o registro")
+	}
+	if target.InTx != nil {
+		if err := target.InTx(ctx, tx, &updated); err != nil {
+			log.Printf("Erro ao aplicar patch em %s %d: %v", target.Table, id, err)
+			return internalError(c, "Erro ao atualizar o registro")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar atualização")
+	}
+	if target.OnUpdated != nil {
+		target.OnUpdated(c, &updated)
+	}
+	setVersionETag(c, target.Version(&updated))
+	return c.JSON(updated)
+}
+
+// endpoint patch ligacao
+func (app *App) patchLigacao(c *fiber.Ctx) error {
+	return patchRecord(app, c, patchTarget[Ligacao]{
+		Table:         "ligacoes",
+		SelectColumns: ligacaoSelectColumns,
+		Fields:        ligacaoPatchFields,
+		Scan:          scanLigacao,
+		Validate:      validateLigacao,
+		Version:       func(l *Ligacao) int { return l.Version },
+		NotFound:      "Ligação não encontrada",
+	})
+}
+
+// endpoint patch avaliacao
+func (app *App) patchAvaliacao(c *fiber.Ctx) error {
+	return patchRecord(app, c, patchTarget[Avaliacao]{
+		Table:         "avaliacoes",
+		SelectColumns: avaliacaoSelectColumns,
+		Fields:        avaliacaoPatchFields,
+		Scan:          scanAvaliacao,
+		Validate:      validateAvaliacao,
+		Version:       func(a *Avaliacao) int { return a.Version },
+		NotFound:      "Avaliação não encontrada",
+	})
+}
+
+// endpoint patch evento agenda
+func (app *App) patchAgendaEvent(c *fiber.Ctx) error {
+	return patchRecord(app, c, patchTarget[AgendaEvent]{
+		Table:         "agenda_events",
+		SelectColumns: agendaEventSelectColumns,
+		Fields:        agendaEventPatchFields,
+		Scan:          scanAgendaEvent,
+		Validate:      validateAgendaEvent,
+		Version:       func(e *AgendaEvent) int { return e.Version },
+		NotFound:      "Evento não encontrado",
+	})
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	BoardEventPresence = "presence"
+
+	// cada instância republica sua presença neste intervalo
+	presenceHeartbeat = 30 * time.Second
+	// presença remota sem heartbeat por mais tempo que isso é descartada
+	presenceRemoteTTL = 3 * presenceHeartbeat
+)
+
+// resumo de usuário exibido para outros clientes
+type UserSummary struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Avatar string `json:"avatar"`
+}
+
+// estado de uma conexão
+type presenceConn struct {
+	User          UserSummary `json:"user"`
+	ViewingCardID *int        `json:"viewing_card_id,omitempty"`
+	EditingCardID *int        `json:"editing_card_id,omitempty"`
+}
+
+// presença agregada por usuário
+type PresenceUser struct {
+	UserSummary
+	ViewingCardIDs []int `json:"viewing_card_ids"`
+	EditingCardIDs []int `json:"editing_card_ids"`
+}
+
+type remotePresence struct {
+	conns     []presenceConn
+	updatedAt time.Time
+}
+
+// registro de presença: conexões locais + snapshots de outras instâncias
+type PresenceRegistry struct {
+	mu     sync.Mutex
+	local  map[int]map[*wsClient]*presenceConn
+	remote map[int]map[string]remotePresence
+}
+
+func NewPresenceRegistry() *PresenceRegistry {
+	return &PresenceRegistry{
+		local:  make(map[int]map[*wsClient]*presenceConn),
+		remote: make(map[int]map[string]remotePresence),
+	}
+}
+
+// mensagem enviada pelo cliente
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Payload struct {
+		CardID int `json:"card_id"`
+	} `json:"payload"`
+}
+
+// buscar nome e avatar do usuário
+func (app *App) getUserSummary(userID string) UserSummary {
+	summary := UserSummary{ID: userID}
+	var email, username, avatar string
+	err := app.db.QueryRow(context.Background(), `
+		SELECT email, COALESCE(raw_user_meta_data->>'username', email), COALESCE(raw_user_meta_data->>'avatar_url', '')
+		FROM auth.users WHERE id = $1`, userID).Scan(&email, &username, &avatar)
+	if err != nil {
+		log.Printf("Aviso: não foi possível encontrar o usuário %s: %v", userID, err)
+		summary.Name = "Um usuário"
+		return summary
+	}
+	summary.Name = username
+	if name, ok := userDisplayNameMap[email]; ok {
+		summary.Name = name
+	}
+	summary.Avatar = avatar
+	return summary
+}
+
+// conexão entrou no board
+func (app *App) presenceJoin(client *wsClient, user UserSummary) {
+	app.presence.mu.Lock()
+	if app.presence.local[client.boardID] == nil {
+		app.presence.local[client.boardID] = make(map[*wsClient]*presenceConn)
+	}
+	app.presence.local[client.boardID][client] = &presenceConn{User: user}
+	app.presence.mu.Unlock()
+	app.presenceChanged(client.boardID)
+}
+
+// conexão saiu do board
+func (app *App) presenceLeave(client *wsClient) {
+	app.presence.mu.Lock()
+	if conns, ok := app.presence.local[client.boardID]; ok {
+		delete(conns, client)
+		if len(conns) == 0 {
+			delete(app.presence.local, client.boardID)
+		}
+	}
+	app.presence.mu.Unlock()
+	app.presenceChanged(client.boardID)
+}
+
+// tratar mensagens VIEWING_CARD / EDITING_CARD / STOPPED_EDITING / STOPPED_VIEWING
+func (app *App) handleClientMessage(client *wsClient, data []byte) {
+	var msg wsClientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	cardID := msg.Payload.CardID
+	switch msg.Type {
+	case "VIEWING_CARD", "EDITING_CARD":
+		boardID, err := app.getBoardIDFromCard(cardID)
+		if err != nil || boardID != client.boardID {
+			return
+		}
+		// só quem pode editar aparece como editando
+		if msg.Type == "EDITING_CARD" {
+			role, err := app.lookup().boardRole(client.userID, client.boardID)
+			if err != nil || !role.AtLeast(RoleEditor) {
+				return
+			}
+		}
+	case "STOPPED_EDITING", "STOPPED_VIEWING":
+	default:
+		return
+	}
+
+	app.presence.mu.Lock()
+	conn, ok := app.presence.local[client.boardID][client]
+	if !ok {
+		app.presence.mu.Unlock()
+		return
+	}
+	switch msg.Type {
+	case "VIEWING_CARD":
+		conn.ViewingCardID = &cardID
+		if conn.EditingCardID != nil && *conn.EditingCardID != cardID {
+			conn.EditingCardID = nil
+		}
+	case "EDITING_CARD":
+		conn.ViewingCardID = &cardID
+		conn.EditingCardID = &cardID
+	case "STOPPED_EDITING":
+		conn.EditingCardID = nil
+	case "STOPPED_VIEWING":
+		conn.ViewingCardID = nil
+		conn.EditingCardID = nil
+	}
+	app.presence.mu.Unlock()
+	app.presenceChanged(client.boardID)
+}
+
+// publicar estado local e avisar clientes locais
+func (app *App) presenceChanged(boardID int) {
+	app.publishLocalPresence(boardID)
+	app.broadcastPresence(boardID)
+}
+
+func (app *App) publishLocalPresence(boardID int) {
+	app.presence.mu.Lock()
+	conns := make([]presenceConn, 0, len(app.presence.local[boardID]))
+	for _, conn := range app.presence.local[boardID] {
+		conns = append(conns, *conn)
+	}
+	app.presence.mu.Unlock()
+
+	data, err := json.Marshal(conns)
+	if err != nil {
+		return
+	}
+	app.publishBoardEvent(BoardEvent{Kind: BoardEventPresence, BoardID: boardID, Message: data})
+}
+
+// presença recebida de outra instância
+func (app *App) applyRemotePresence(ev BoardEvent) {
+	var conns []presenceConn
+	if err := json.Unmarshal(ev.Message, &conns); err != nil {
+		return
+	}
+	app.presence.mu.Lock()
+	if len(conns) == 0 {
+		delete(app.presence.remote[ev.BoardID], ev.Origin)
+	} else {
+		if app.presence.remote[ev.BoardID] == nil {
+			app.presence.remote[ev.BoardID] = make(map[string]remotePresence)
+		}
+		app.presence.remote[ev.BoardID][ev.Origin] = remotePresence{conns: conns, updatedAt: time.Now()}
+	}
+	app.presence.mu.Unlock()
+	app.broadcastPresence(ev.BoardID)
+}
+
+// enviar snapshot só para os sockets locais (não entra no log de eventos)
+func (app *App) broadcastPresence(boardID int) {
+	data, err := json.Marshal(WsMessage{
+		Type:    "PRESENCE_CHANGED",
+		Payload: fiber.Map{"board_id": boardID, "users": app.presenceSnapshot(boardID)},
+	})
+	if err != nil {
+		return
+	}
+	app.hub.Broadcast(boardID, 0, data)
+}
+
+// snapshot agregado por usuário
+func (app *App) presenceSnapshot(boardID int) []PresenceUser {
+	app.presence.mu.Lock()
+	conns := make([]presenceConn, 0)
+	for _, conn := range app.presence.local[boardID] {
+		conns = append(conns, *conn)
+	}
+	for origin, rp := range app.presence.remote[boardID] {
+		if time.Since(rp.updatedAt) > presenceRemoteTTL {
+			delete(app.presence.remote[boardID], origin)
+			continue
+		}
+		conns = append(conns, rp.conns...)
+	}
+	app.presence.mu.Unlock()
+
+	byUser := make(map[string]*PresenceUser)
+	users := make([]PresenceUser, 0)
+	order := make([]string, 0)
+	for _, conn := range conns {
+		pu, ok := byUser[conn.User.ID]
+		if !ok {
+			pu = &PresenceUser{UserSummary: conn.User, ViewingCardIDs: []int{}, EditingCardIDs: []int{}}
+			byUser[conn.User.ID] = pu
+			order = append(order, conn.User.ID)
+		}
+		if conn.ViewingCardID != nil && !containsInt(pu.ViewingCardIDs, *conn.ViewingCardID) {
+			pu.ViewingCardIDs = append(pu.ViewingCardIDs, *conn.ViewingCardID)
+		}
+		if conn.EditingCardID != nil && !containsInt(pu.EditingCardIDs, *conn.EditingCardID) {
+			pu.EditingCardIDs = append(pu.EditingCardIDs, *conn.EditingCardID)
+		}
+	}
+	sort.Slice(order, func(i, j int) bool { return byUser[order[i]].Name < byUser[order[j]].Name })
+	for _, id := range order {
+		users = append(users, *byUser[id])
+	}
+	return users
+}
+
+// republicar presença local periodicamente para renovar o TTL nas outras instâncias
+func (app *App) runPresenceHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			app.presence.mu.Lock()
+			boardIDs := make([]int, 0, len(app.presence.local))
+			for boardID := range app.presence.local {
+				boardIDs = append(boardIDs, boardID)
+			}
+			app.presence.mu.Unlock()
+			for _, boardID := range boardIDs {
+				app.publishLocalPresence(boardID)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// endpoint presença do board
+func (app *App) getBoardPresence(c *fiber.Ctx) error {
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID de board inválido")
+	}
+	userID := c.Locals("userID").(string)
+	hasPermission, err := app.checkBoardPermission(userID, boardID)
+	if err != nil || !hasPermission {
+		return forbidden(c)
+	}
+	return c.JSON(fiber.Map{"board_id": boardID, "users": app.presenceSnapshot(boardID)})
+}
+
+func containsInt(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
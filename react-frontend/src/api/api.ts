@@ -20,4 +20,15 @@ export async function api(path: string, options: RequestInit = {}): Promise<Resp
     }
 
     return response;
-}
\ No newline at end of file
+}
+
+// Extrai a mensagem do corpo de erro padrão ({ error: { code, message, fields } })
+export async function apiErrorMessage(response: Response, fallback: string): Promise<string> {
+    const data = await response.json().catch(() => null);
+    const error = data?.error;
+    if (!error?.message) return fallback;
+    const fields = Array.isArray(error.fields) && error.fields.length > 0
+        ? ': ' + error.fields.map((f: { field: string, message: string }) => `${f.field} ${f.message}`).join(', ')
+        : '';
+    return error.message + fields;
+}
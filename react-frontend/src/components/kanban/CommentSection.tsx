@@ -1,47 +1,55 @@
 import React, { useState } from 'react';
 import toast from 'react-hot-toast';
-import { Comment } from '../../types/kanban';
+import { CardComment, CommentSectionName } from '../../types/kanban';
 import { useBoard } from '../../contexts/BoardContext';
-import { userDisplayNameMap } from '../../api/config';
+import { useAuth } from '../../contexts/AuthContext';
+import * as commentService from '../../services/comments';
 import styles from './CommentSection.module.css'; // Importando o módulo CSS
 
 interface CommentSectionProps {
   title: string;
   icon: string;
-  comments: Comment[];
-  onCommentsChange: (newComments: Comment[]) => void;
-  authorName: string; 
+  cardId: number;
+  section: CommentSectionName | null;
+  comments: CardComment[];
+  onCommentsChange: (update: (comments: CardComment[]) => CardComment[]) => void;
 }
 
-export function CommentSection({ title, icon, comments, onCommentsChange, authorName }: CommentSectionProps) {
+export function CommentSection({ title, icon, cardId, section, comments, onCommentsChange }: CommentSectionProps) {
     const [isInputVisible, setInputVisible] = useState(false);
     const [newCommentText, setNewCommentText] = useState('');
-    const [editingIndex, setEditingIndex] = useState<number | null>(null);
+    const [editingId, setEditingId] = useState<number | null>(null);
     const [editingText, setEditingText] = useState('');
 
     const { users, boardMembers, board } = useBoard();
+    const { user: currentUser } = useAuth();
 
-    const handleAddComment = () => {
+    const handleAddComment = async () => {
         if (!newCommentText.trim()) return;
-        const newComment: Comment = {
-            text: newCommentText,
-            author: authorName,
-            timestamp: new Date().toLocaleString('pt-BR'),
-        };
-        onCommentsChange([...(comments || []), newComment]);
-        setNewCommentText('');
-        setInputVisible(false);
+        try {
+            const created = await commentService.createCardComment(cardId, newCommentText, section);
+            onCommentsChange(list => [...list, created]);
+            setNewCommentText('');
+            setInputVisible(false);
+        } catch (error: any) {
+            toast.error(error.message || "Falha ao adicionar comentário.");
+        }
     };
 
-    const handleDeleteComment = (indexToDelete: number) => {
+    const handleDeleteComment = (commentId: number) => {
         toast((t) => (
             <span>
                 Excluir este comentário?
                 <div style={{ marginTop: '10px', display: 'flex', gap: '8px' }}>
-                    <button className="btn btn-danger" style={{flex: 1}} onClick={() => {
-                        onCommentsChange(comments.filter((_, index) => index !== indexToDelete));
+                    <button className="btn btn-danger" style={{flex: 1}} onClick={async () => {
                         toast.dismiss(t.id);
-                        toast.success("Comentário removido.");
+                        try {
+                            await commentService.deleteCardComment(cardId, commentId);
+                            onCommentsChange(list => list.filter(c => c.id !== commentId));
+                            toast.success("Comentário removido.");
+                        } catch (error: any) {
+                            toast.error(error.message || "Falha ao remover comentário.");
+                        }
                     }}>Sim</button>
                     <button className="btn btn-secondary" style={{flex: 1}} onClick={() => toast.dismiss(t.id)}>Não</button>
                 </div>
@@ -49,24 +57,26 @@ export function CommentSection({ title, icon, comments, onCommentsChange, author
         ));
     };
 
-    const handleStartEditing = (index: number, text: string) => {
-        setEditingIndex(index);
+    const handleStartEditing = (commentId: number, text: string) => {
+        setEditingId(commentId);
         setEditingText(text);
     };
 
     const handleCancelEditing = () => {
-        setEditingIndex(null);
+        setEditingId(null);
         setEditingText('');
     };
 
-    const handleSaveEdit = () => {
-        if (editingIndex === null || !editingText.trim()) return;
-        const updatedComments = comments.map((comment, index) => 
-            index === editingIndex ? { ...comment, text: editingText } : comment
-        );
-        onCommentsChange(updatedComments);
-        handleCancelEditing();
-        toast.success("Comentário atualizado.");
+    const handleSaveEdit = async () => {
+        if (editingId === null || !editingText.trim()) return;
+        try {
+            const updated = await commentService.updateCardComment(cardId, editingId, editingText);
+            onCommentsChange(list => list.map(c => c.id === updated.id ? updated : c));
+            handleCancelEditing();
+            toast.success("Comentário atualizado.");
+        } catch (error: any) {
+            toast.error(error.message || "Falha ao atualizar comentário.");
+        }
     };
 
     return (
@@ -76,13 +86,13 @@ export function CommentSection({ title, icon, comments, onCommentsChange, author
                 <button type="button" className={styles.addCommentBtn} onClick={() => setInputVisible(s => !s)}><i className="fas fa-plus"></i></button>
             </div>
             <div className={styles.commentsList}>
-                {comments && comments.map((comment, index) => {
-                    const authorUser = (board?.is_public ? users : boardMembers).find(u => (userDisplayNameMap[u.email] || u.username) === comment.author);
-                    const canEdit = comment.author === authorName; 
+                {comments.map((comment) => {
+                    const authorUser = (board?.is_public ? users : boardMembers).find(u => u.id === comment.author_id);
+                    const canEdit = !!currentUser && comment.author_id === currentUser.id;
 
-                    if (editingIndex === index && canEdit) {
+                    if (editingId === comment.id && canEdit) {
                         return (
-                            <div key={index} className={styles.commentEditContainer}>
+                            <div key={comment.id} className={styles.commentEditContainer}>
                                 <textarea className={styles.commentEditTextarea} value={editingText} onChange={(e) => setEditingText(e.target.value)} rows={3}></textarea>
                                 <div className={styles.commentActions}>
                                     <button type="button" className={styles.btnSave} onClick={handleSaveEdit}><i className="fas fa-check"></i> Salvar</button>
@@ -93,7 +103,7 @@ export function CommentSection({ title, icon, comments, onCommentsChange, author
                     }
 
                     return (
-                        <div key={index} className={styles.commentItem}>
+                        <div key={comment.id} className={styles.commentItem}>
                             <div className={styles.commentContent}>{comment.text}</div>
                             <div className={styles.commentMeta}>
                                 <span className={styles.commentAuthor}>
@@ -102,12 +112,12 @@ export function CommentSection({ title, icon, comments, onCommentsChange, author
                                     </div>
                                     {comment.author}
                                 </span>
-                                <span className={styles.commentTimestamp}>{comment.timestamp}</span>
+                                <span className={styles.commentTimestamp}>{new Date(comment.created_at).toLocaleString('pt-BR')}</span>
                             </div>
                             {canEdit && (
                                 <div className={styles.commentItemActions}>
-                                    <button className={styles.editCommentBtn} onClick={() => handleStartEditing(index, comment.text)} title="Editar Comentário"><i className="fas fa-pencil-alt"></i></button>
-                                    <button className={styles.deleteCommentBtn} onClick={() => handleDeleteComment(index)} title="Excluir Comentário"><i className="fas fa-trash"></i></button>
+                                    <button className={styles.editCommentBtn} onClick={() => handleStartEditing(comment.id, comment.text)} title="Editar Comentário"><i className="fas fa-pencil-alt"></i></button>
+                                    <button className={styles.deleteCommentBtn} onClick={() => handleDeleteComment(comment.id)} title="Excluir Comentário"><i className="fas fa-trash"></i></button>
                                 </div>
                             )}
                         </div>
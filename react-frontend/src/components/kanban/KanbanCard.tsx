@@ -160,11 +160,21 @@ export function KanbanCard({ card, isOverlay = false }: CardProps) {
     }
 
     try {
-      await cardService.updateCard(card.id, updatedCardData);
+      if (board.is_public) {
+        // a troca de coluna passa pelo /cards/move, que também define completed_at
+        await cardService.moveCard(card.id, updatedCardData.column_id, 0);
+      } else {
+        updatedCardData = await cardService.patchCard(card.id, { completed_at: updatedCardData.completed_at }, card.version);
+      }
       updateCard(updatedCardData);
       toast.success(successMessage);
     } catch (error) {
-      toast.error("Não foi possível atualizar a tarefa.");
+      if (error instanceof cardService.CardConflictError) {
+        updateCard(error.current);
+        toast.error(error.message);
+      } else {
+        toast.error("Não foi possível atualizar a tarefa.");
+      }
     }
   };
 
@@ -1,13 +1,12 @@
-import React, { useState, useEffect, useCallback } from 'react';
+import React, { useState, useEffect, useCallback, useRef } from 'react';
 import toast from 'react-hot-toast';
 import { debounce } from 'lodash';
 
 import { useModal } from '../../contexts/ModalContext';
 import { useBoard } from '../../contexts/BoardContext';
-import { useAuth } from '../../contexts/AuthContext';
-import { Card, Comment } from '../../types/kanban';
+import { Card, CardComment, CommentSectionName } from '../../types/kanban';
 import * as cardService from '../../services/cards';
-import { userDisplayNameMap } from '../../api/config';
+import * as commentService from '../../services/comments';
 
 import { AssigneeSelector } from '../kanban/AssigneeSelector';
 import { CommentSection } from '../kanban/CommentSection';
@@ -16,8 +15,7 @@ import styles from './TaskModal.module.css';
 
 export function TaskModal() {
     const { isModalOpen, closeModal, isClosing, editingCard, currentColumnId } = useModal();
-    const { board, columns, users, boardMembers, solucionadoId, naoSolucionadoId, fetchBoardData } = useBoard();
-    const { user: currentUser } = useAuth();
+    const { board, columns, users, boardMembers, solucionadoId, naoSolucionadoId, fetchBoardData, updateCard } = useBoard();
     
     const [title, setTitle] = useState('');
     const [priority, setPriority] = useState<'baixa' | 'media' | 'alta'>('media');
@@ -29,44 +27,45 @@ export function TaskModal() {
     
     const [isIndicatorVisible, setIsIndicatorVisible] = useState(false);
     
-    const [observacoes, setObservacoes] = useState<Comment[]>([]);
-    const [tentativas, setTentativas] = useState<Comment[]>([]);
-    const [resolucao, setResolucao] = useState<Comment[]>([]);
-    const [privateComments, setPrivateComments] = useState<Comment[]>([]);
+    const [comments, setComments] = useState<CardComment[]>([]);
+    // último estado salvo no servidor; o autosave manda só o que mudou desde ele
+    const savedRef = useRef<cardService.CardPatch>({});
+    // versão do card que o modal está editando; vai no If-Match
+    const versionRef = useRef<number | undefined>(undefined);
 
     const isEditing = !!editingCard;
 
+    // preencher o formulário e o estado salvo a partir de uma cópia do servidor
+    const loadCard = (card: Card) => {
+        savedRef.current = {
+            title: card.title || '',
+            priority: card.priority || 'media',
+            assigned_to: card.assigned_to || '',
+            due_date: card.due_date,
+        };
+        versionRef.current = card.version;
+        setTitle(card.title || '');
+        setPriority(card.priority || 'media');
+        setAssignee(card.assigned_to || null);
+        if (card.due_date) {
+            const localDate = new Date(card.due_date);
+            localDate.setMinutes(localDate.getMinutes() - localDate.getTimezoneOffset());
+            setDueDate(localDate.toISOString().slice(0, 16));
+        } else {
+            setDueDate('');
+        }
+    };
+
     useEffect(() => {
         if (isEditing && editingCard && board) {
-            setTitle(editingCard.title || '');
-            setPriority(editingCard.priority || 'media');
-            setAssignee(editingCard.assigned_to || null);
-            if (editingCard.due_date) {
-                const localDate = new Date(editingCard.due_date);
-                localDate.setMinutes(localDate.getMinutes() - localDate.getTimezoneOffset());
-                setDueDate(localDate.toISOString().slice(0, 16));
-            } else {
-                setDueDate('');
-            }
-            
-            try {
-                const desc = JSON.parse(editingCard.description || '{}');
-                if(board.is_public) {
-                    setObservacoes(desc.observacoes || []);
-                    setTentativas(desc.tentativas || []);
-                    setResolucao(desc.resolucao || []);
-                } else {
-                    setPrivateComments(desc.comments || []);
-                }
-            } catch {
-                const fallbackComment = { text: editingCard.description, author: 'Sistema', timestamp: '' };
-                if(board.is_public) setObservacoes([fallbackComment]);
-                else setPrivateComments([fallbackComment]);
-            }
+            loadCard(editingCard);
+            setComments([]);
+            commentService.getCardComments(editingCard.id)
+                .then(setComments)
+                .catch(() => toast.error("Não foi possível carregar os comentários."));
         } else {
             setTitle(''); setPriority('media'); setDueDate(''); setAssignee(null);
-            setInitialDescription(''); setObservacoes([]); setTentativas([]);
-            setResolucao([]); setPrivateComments([]);
+            setInitialDescription(''); setComments([]);
         }
         setIsDirty(false);
     }, [isModalOpen, editingCard, board]);
@@ -83,39 +82,54 @@ export function TaskModal() {
         }
     }, [isSaving]);
     
-    const getAuthorName = useCallback(() => (currentUser && (userDisplayNameMap[currentUser.email!] || currentUser.email!)) || "Usuário", [currentUser]);
-
     const saveChanges = useCallback(async () => {
         if (!isEditing || !editingCard || !board) return;
+        // PATCH só com os campos alterados: completed_at e a descrição nunca são sobrescritos daqui
+        const saved = savedRef.current;
+        const nextDueDate = dueDate ? new Date(dueDate).toISOString() : null;
+        const changes: cardService.CardPatch = {};
+        if (title !== saved.title) changes.title = title;
+        if (priority !== saved.priority) changes.priority = priority;
+        if ((assignee ?? '') !== saved.assigned_to) changes.assigned_to = assignee ?? '';
+        if (!sameInstant(nextDueDate, saved.due_date ?? null)) changes.due_date = nextDueDate;
+        if (Object.keys(changes).length === 0) { setIsDirty(false); return; }
+
         setIsSaving(true);
-        const descriptionJson = board.is_public ? JSON.stringify({ observacoes, tentativas, resolucao }) : JSON.stringify({ comments: privateComments });
-        const cardData: Partial<Card> = { title, priority, assigned_to: assignee ?? undefined, due_date: dueDate ? new Date(dueDate).toISOString() : null, description: descriptionJson };
-        
         try {
-            await cardService.updateCard(editingCard.id, cardData);
+            const updated = await cardService.patchCard(editingCard.id, changes, versionRef.current);
+            savedRef.current = { title: updated.title, priority: updated.priority, assigned_to: updated.assigned_to || '', due_date: updated.due_date };
+            versionRef.current = updated.version;
             setIsDirty(false);
-        } catch (error) { toast.error("Falha no salvamento automático.");
+        } catch (error) {
+            if (error instanceof cardService.CardConflictError) {
+                // outra pessoa salvou antes: mostrar a cópia atual em vez de sobrescrever
+                loadCard(error.current);
+                updateCard(error.current);
+                setIsDirty(false);
+                toast.error(error.message);
+            } else {
+                toast.error("Falha no salvamento automático.");
+            }
         } finally { setIsSaving(false); }
-    }, [isEditing, editingCard, board, title, priority, dueDate, assignee, observacoes, tentativas, resolucao, privateComments]);
+    }, [isEditing, editingCard, board, title, priority, dueDate, assignee, updateCard]);
 
     const debouncedSave = useCallback(debounce(saveChanges, 2000), [saveChanges]);
 
     useEffect(() => {
         if (isEditing && isDirty) debouncedSave();
-    }, [isEditing, isDirty, title, priority, dueDate, assignee, observacoes, tentativas, resolucao, privateComments, debouncedSave]);
+    }, [isEditing, isDirty, title, priority, dueDate, assignee, debouncedSave]);
     
     const handleSubmitNewTask = async (e: React.FormEvent) => {
         e.preventDefault();
         const toastId = toast.loading("Criando tarefa...");
         const cardData = {
             title, priority, assigned_to: assignee ?? undefined, due_date: dueDate ? new Date(dueDate).toISOString() : null,
-            description: JSON.stringify({
-                observacoes: initialDescription ? [{ text: initialDescription, author: getAuthorName(), timestamp: new Date().toLocaleString('pt-BR') }] : [],
-                tentativas: [], resolucao: []
-            })
         };
         try {
-            await cardService.createCard(currentColumnId!, cardData);
+            const created = await cardService.createCard(currentColumnId!, cardData);
+            if (initialDescription.trim()) {
+                await commentService.createCardComment(created.id, initialDescription, board!.is_public ? 'observacoes' : null);
+            }
             await fetchBoardData(board!.id, !board!.is_public);
             toast.success("Tarefa criada!", { id: toastId });
             closeModal();
@@ -190,6 +204,7 @@ export function TaskModal() {
     if (!board) return <div className={styles.modal} style={{display:'flex'}}><div className={styles.modalContent} style={{width:'200px',height:'200px'}}><Loader isVisible={true}/></div></div>;
 
     const userSource = board.is_public ? users : boardMembers;
+    const sectionComments = (section: CommentSectionName | null) => comments.filter(c => (c.section ?? null) === section);
     const isArchived = editingCard && (editingCard.column_id === solucionadoId || editingCard.column_id === naoSolucionadoId);
 
     return (
@@ -270,12 +285,12 @@ export function TaskModal() {
                             <div className={styles.commentsContainer}>
                                 {board.is_public ? (
                                     <>
-                                        <CommentSection title="Observações" icon="fa-eye" comments={observacoes} onCommentsChange={(c) => { setObservacoes(c); setIsDirty(true); }} authorName={getAuthorName()} />
-                                        <CommentSection title="Tentativas de Contato" icon="fa-phone" comments={tentativas} onCommentsChange={(c) => { setTentativas(c); setIsDirty(true); }} authorName={getAuthorName()} />
-                                        <CommentSection title="Resolução" icon="fa-check-circle" comments={resolucao} onCommentsChange={(c) => { setResolucao(c); setIsDirty(true); }} authorName={getAuthorName()} />
+                                        <CommentSection title="Observações" icon="fa-eye" cardId={editingCard!.id} section="observacoes" comments={sectionComments('observacoes')} onCommentsChange={setComments} />
+                                        <CommentSection title="Tentativas de Contato" icon="fa-phone" cardId={editingCard!.id} section="tentativas" comments={sectionComments('tentativas')} onCommentsChange={setComments} />
+                                        <CommentSection title="Resolução" icon="fa-check-circle" cardId={editingCard!.id} section="resolucao" comments={sectionComments('resolucao')} onCommentsChange={setComments} />
                                     </>
                                 ) : (
-                                    <CommentSection title="Comentários" icon="fa-comment-dots" comments={privateComments} onCommentsChange={(c) => { setPrivateComments(c); setIsDirty(true); }} authorName={getAuthorName()} />
+                                    <CommentSection title="Comentários" icon="fa-comment-dots" cardId={editingCard!.id} section={null} comments={sectionComments(null)} onCommentsChange={setComments} />
                                 )}
                             </div>
                         </div>
@@ -284,4 +299,9 @@ export function TaskModal() {
             </div>
         </div>
     );
-}
\ No newline at end of file
+}
+
+function sameInstant(a: string | null, b: string | null): boolean {
+    if (!a || !b) return a === b;
+    return new Date(a).getTime() === new Date(b).getTime();
+}
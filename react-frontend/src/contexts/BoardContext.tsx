@@ -2,15 +2,14 @@ import { createContext, useContext, useState, useEffect, ReactNode, useCallback
 import toast from 'react-hot-toast';
 
 import * as boardService from '../services/boards';
-import * as columnService from '../services/columns';
-import * as cardService from '../services/cards';
 import * as userService from '../services/users';
-import type { Board, Column, Card, User } from '../types/kanban';
+import type { Board, Column, Card, Label, User } from '../types/kanban';
 import { useWebSocket } from '../hooks/useWebSocket';
 
 interface BoardContextType {
   board: Board | null;
   columns: Column[];
+  labels: Label[];
   users: User[];
   boardMembers: User[];
   setBoardMembers: React.Dispatch<React.SetStateAction<User[]>>;
@@ -28,11 +27,22 @@ interface BoardContextType {
   removeCard: (cardId: number, columnId: number) => void;
 }
 
+// eventos de card nem sempre trazem etiquetas e responsáveis; mantém os que já estavam
+function keepCardDetails(card: Card, previous?: Card): Card {
+  if (!previous) return card;
+  return {
+    ...card,
+    labels: card.labels ?? previous.labels,
+    assignees: card.assignees ?? previous.assignees,
+  };
+}
+
 const BoardContext = createContext<BoardContextType | undefined>(undefined);
 
 export function BoardProvider({ children }: { children: ReactNode }) {
   const [board, setBoard] = useState<Board | null>(null);
   const [columns, setColumns] = useState<Column[]>([]);
+  const [labels, setLabels] = useState<Label[]>([]);
   const [isColumnDragging, setIsColumnDragging] = useState(false);
   const [users, setUsers] = useState<User[]>([]);
   const [boardMembers, setBoardMembers] = useState<User[]>([]);
@@ -43,30 +53,21 @@ export function BoardProvider({ children }: { children: ReactNode }) {
   const fetchBoardData = useCallback(async (boardId: number, isPrivate = false) => {
     try {
       setIsLoading(true);
-      const boardDetailsPromise = (isPrivate ? boardService.getPrivateBoards() : boardService.getPublicBoards())
-        .then(boards => {
-            const foundBoard = boards.find(b => b.id === boardId);
-            if (!foundBoard) throw new Error(`Quadro com ID ${boardId} não encontrado.`);
-            return foundBoard;
-        });
-
-      const [boardDetails, fetchedColumns, members] = await Promise.all([
-        boardDetailsPromise,
-        columnService.getColumnsForBoard(boardId),
+      setSolucionadoId(null);
+      setNaoSolucionadoId(null);
+      const [snapshot, members] = await Promise.all([
+        boardService.getBoardFull(boardId),
         isPrivate ? boardService.getBoardMembers(boardId) : Promise.resolve([])
       ]);
       
       setBoardMembers(members || []);
-      const columnsWithCards = await Promise.all(
-        fetchedColumns.map(async (col) => {
-          if(col.title.toLowerCase() === 'solucionado') setSolucionadoId(col.id);
-          if(col.title.toLowerCase() === 'não solucionado') setNaoSolucionadoId(col.id);
-          const cards = await cardService.getCardsForColumn(col.id);
-          return { ...col, cards: cards || [] };
-        })
-      );
-      setBoard(boardDetails);
-      setColumns(columnsWithCards);
+      snapshot.columns.forEach(col => {
+        if(col.title.toLowerCase() === 'solucionado') setSolucionadoId(col.id);
+        if(col.title.toLowerCase() === 'não solucionado') setNaoSolucionadoId(col.id);
+      });
+      setBoard(snapshot.board);
+      setLabels(snapshot.labels || []);
+      setColumns(snapshot.columns);
     } catch (error: any) {
       toast.error(error.message || "Falha ao carregar dados do quadro.");
     } finally {
@@ -76,6 +77,8 @@ export function BoardProvider({ children }: { children: ReactNode }) {
 
   const updateCard = useCallback((updatedCard: Card) => {
     setColumns(prev => {
+        const previous = prev.flatMap(col => col.cards).find(c => c.id === updatedCard.id);
+        updatedCard = keepCardDetails(updatedCard, previous);
         const colsWithCardRemoved = prev.map(col => ({
             ...col,
             cards: col.cards.filter(c => c.id !== updatedCard.id)
@@ -99,7 +102,15 @@ export function BoardProvider({ children }: { children: ReactNode }) {
     switch (message.type) {
       case 'CARD_UPDATED': {
         const updatedCard = message.payload as Card;
-        updateCard(updatedCard); 
+        updateCard(updatedCard);
+        break;
+      }
+      case 'CARD_PATCHED': {
+        const { id, version, updated_at, changes } = message.payload as { id: number, version: number, updated_at: string, changes: Partial<Card> };
+        setColumns(prev => prev.map(col => ({
+          ...col,
+          cards: col.cards.map(c => c.id === id ? { ...c, ...changes, version, updated_at } : c)
+        })));
         break;
       }
       case 'CARD_CREATED': {
@@ -112,6 +123,56 @@ export function BoardProvider({ children }: { children: ReactNode }) {
         setColumns(prev => prev.map(col => ({...col, cards: col.cards.filter(c => c.id !== card_id)})));
         break;
       }
+      case 'CARDS_BULK_UPDATED': {
+        const { cards, removed_card_ids } = message.payload as { cards: Card[], removed_card_ids: number[] };
+        const dropIds = new Set<number>([...removed_card_ids, ...cards.map(c => c.id)]);
+        setColumns(prev => {
+          const previous = new Map(prev.flatMap(col => col.cards).map(c => [c.id, c]));
+          return prev.map(col => {
+            const incoming = cards.filter(c => c.column_id === col.id).map(c => keepCardDetails(c, previous.get(c.id)));
+            const kept = col.cards.filter(c => !dropIds.has(c.id));
+            if (incoming.length === 0 && kept.length === col.cards.length) return col;
+            return { ...col, cards: [...kept, ...incoming].sort((a, b) => a.position - b.position) };
+          });
+        });
+        break;
+      }
+      case 'CARD_LABELS_UPDATED': {
+        const { card_id, labels: cardLabels } = message.payload as { card_id: number, labels: Label[] };
+        setColumns(prev => prev.map(col => ({
+          ...col,
+          cards: col.cards.map(c => c.id === card_id ? { ...c, labels: cardLabels } : c)
+        })));
+        break;
+      }
+      case 'LABEL_CREATED': {
+        const label = message.payload as Label;
+        setLabels(prev => [...prev.filter(l => l.id !== label.id), label].sort((a, b) => a.name.localeCompare(b.name)));
+        break;
+      }
+      case 'LABEL_UPDATED': {
+        const label = message.payload as Label;
+        setLabels(prev => prev.map(l => l.id === label.id ? label : l).sort((a, b) => a.name.localeCompare(b.name)));
+        setColumns(prev => prev.map(col => ({
+          ...col,
+          cards: col.cards.map(c => c.labels?.some(l => l.id === label.id) ? { ...c, labels: c.labels.map(l => l.id === label.id ? label : l) } : c)
+        })));
+        break;
+      }
+      case 'LABEL_DELETED': {
+        const { label_id } = message.payload as { label_id: number };
+        setLabels(prev => prev.filter(l => l.id !== label_id));
+        setColumns(prev => prev.map(col => ({
+          ...col,
+          cards: col.cards.map(c => c.labels?.some(l => l.id === label_id) ? { ...c, labels: c.labels.filter(l => l.id !== label_id) } : c)
+        })));
+        break;
+      }
+      case 'CARDS_ARCHIVED': {
+        const archivedIds = new Set<number>(message.payload.card_ids);
+        setColumns(prev => prev.map(col => ({...col, cards: col.cards.filter(c => !archivedIds.has(c.id))})));
+        break;
+      }
       
        case 'CARD_MOVED': {
         const { card, old_column_id } = message.payload as { card: Card, old_column_id: number };
@@ -161,6 +222,7 @@ export function BoardProvider({ children }: { children: ReactNode }) {
       }
 
       case 'BOARD_STATE_UPDATED':
+      case 'RESYNC_REQUIRED':
       case 'COLUMN_CREATED':
       case 'COLUMN_UPDATED':
       case 'COLUMN_DELETED':
@@ -198,6 +260,7 @@ export function BoardProvider({ children }: { children: ReactNode }) {
   const value = { 
     board, 
     columns, 
+    labels,
     users, 
     boardMembers, 
     setBoardMembers, 
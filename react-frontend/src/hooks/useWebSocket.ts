@@ -1,5 +1,11 @@
 import { useEffect, useRef } from 'react';
 import { useAuth } from '../contexts/AuthContext';
+import { supabaseClient } from '../api/supabaseClient';
+
+// código de close enviado quando o acesso ao board é revogado; não reconectar
+const WS_CLOSE_ACCESS_REVOKED = 4403;
+const RECONNECT_BASE_DELAY = 1000;
+const RECONNECT_MAX_DELAY = 30000;
 
 export function useWebSocket(boardId: number | undefined, onMessage: (message: any) => void) {
   const ws = useRef<WebSocket | null>(null);
@@ -17,40 +23,104 @@ export function useWebSocket(boardId: number | undefined, onMessage: (message: a
     }
 
     const isProduction = process.env.NODE_ENV === 'production';
-    const host = window.location.host; 
+    const host = window.location.host;
     const protocol = window.location.protocol === "https:" ? "wss" : "ws";
 
-    const wsUrl = isProduction 
+    const wsUrl = isProduction
       ? `${protocol}://${host}/ws/board/${boardId}`
       : `${protocol}://${window.location.hostname}:10000/ws/board/${boardId}`;
-    
-    ws.current = new WebSocket(wsUrl);
-
-    ws.current.onopen = () => console.log(`[WebSocket] Conectado ao board ${boardId}`);
-    
-    ws.current.onmessage = (event) => {
-      try {
-        const messageData = JSON.parse(event.data);
-        
-        if (messageData.sender_id && messageData.sender_id === user.id) {
-          return;
-        }
 
-        onMessageRef.current(messageData);
+    let cancelled = false;
+    let attempts = 0;
+    let reconnectTimer: ReturnType<typeof setTimeout> | undefined;
+    // último seq aplicado; na reconexão o servidor reenvia o que foi perdido
+    let lastSeq: number | null = null;
+    // buraco na sequência (eventos de outra instância chegando fora de ordem):
+    // reconectar já com ?since= em vez de esperar o backoff
+    let resyncing = false;
+
+    const scheduleReconnect = () => {
+      if (cancelled) return;
+      const delay = Math.min(RECONNECT_BASE_DELAY * 2 ** attempts, RECONNECT_MAX_DELAY);
+      attempts++;
+      reconnectTimer = setTimeout(connect, delay);
+    };
 
-      } catch (error) {
-        console.error("[WebSocket] Erro ao processar mensagem:", error);
+    const connect = async () => {
+      const { data: { session } } = await supabaseClient.auth.getSession();
+      if (cancelled) return;
+      if (!session?.access_token) {
+        scheduleReconnect();
+        return;
       }
+
+      const url = lastSeq !== null ? `${wsUrl}?since=${lastSeq}` : wsUrl;
+      const socket = new WebSocket(url, ['bearer', session.access_token]);
+      ws.current = socket;
+
+      socket.onopen = () => {
+        attempts = 0;
+        console.log(`[WebSocket] Conectado ao board ${boardId}`);
+      };
+
+      socket.onmessage = (event) => {
+        if (resyncing) return;
+        try {
+          const messageData = JSON.parse(event.data);
+
+          if (messageData.type === 'SYNC_STATE') {
+            if (lastSeq === null) lastSeq = messageData.payload.latest_seq;
+            return;
+          }
+          if (messageData.type === 'RESYNC_REQUIRED') {
+            // o log não cobre o intervalo perdido: recarregar o board inteiro
+            lastSeq = messageData.payload.latest_seq;
+            onMessageRef.current(messageData);
+            return;
+          }
+          if (typeof messageData.seq === 'number') {
+            // já aplicado (replay ou duplicado)
+            if (lastSeq !== null && messageData.seq <= lastSeq) return;
+            if (lastSeq !== null && messageData.seq > lastSeq + 1) {
+              resyncing = true;
+              socket.close();
+              return;
+            }
+            lastSeq = messageData.seq;
+          }
+
+          if (messageData.sender_id && messageData.sender_id === user.id) {
+            return;
+          }
+
+          onMessageRef.current(messageData);
+
+        } catch (error) {
+          console.error("[WebSocket] Erro ao processar mensagem:", error);
+        }
+      };
+
+      socket.onerror = (error) => console.error("[WebSocket] Erro:", error);
+      socket.onclose = (event) => {
+        console.log(`[WebSocket] Desconectado do board ${boardId}`);
+        if (resyncing && !cancelled) {
+          resyncing = false;
+          connect();
+          return;
+        }
+        if (event.code !== WS_CLOSE_ACCESS_REVOKED) scheduleReconnect();
+      };
     };
 
-    ws.current.onerror = (error) => console.error("[WebSocket] Erro:", error);
-    ws.current.onclose = () => console.log(`[WebSocket] Desconectado do board ${boardId}`);
+    connect();
 
     return () => {
-      if (ws.current?.readyState === WebSocket.OPEN) {
-        ws.current?.close();
+      cancelled = true;
+      clearTimeout(reconnectTimer);
+      if (ws.current && ws.current.readyState <= WebSocket.OPEN) {
+        ws.current.close();
       }
     };
-  }, [boardId, user]); 
+  }, [boardId, user]);
 
-}
\ No newline at end of file
+}
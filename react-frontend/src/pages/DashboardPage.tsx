@@ -18,6 +18,8 @@ import { useBoard } from "../contexts/BoardContext";
 import { Card } from "../types/kanban";
 import { userDisplayNameMap } from "../api/config";
 import { Loader } from "../components/ui/Loader";
+import * as boardService from "../services/boards";
+import toast from "react-hot-toast";
 import styles from "./DashboardPage.module.css";
 
 const PALETTE = {
@@ -254,8 +256,10 @@ export function DashboardPage() {
   const { filters, updateFilter } = useFilters();
 
   useEffect(() => {
-    const PUBLIC_BOARD_ID = 8;
-    fetchBoardData(PUBLIC_BOARD_ID, false);
+    boardService
+      .getDefaultPublicBoard()
+      .then((defaultBoard) => fetchBoardData(defaultBoard.id, false))
+      .catch((error) => toast.error(error.message));
   }, [fetchBoardData]);
 
   const stats = useMemo((): DashboardStats | null => {
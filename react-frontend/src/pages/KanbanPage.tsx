@@ -1,22 +1,25 @@
 import { useEffect } from 'react';
 import { useParams } from 'react-router-dom';
+import toast from 'react-hot-toast';
 import { useBoard } from '../contexts/BoardContext';
 import { Loader } from '../components/ui/Loader';
 import { KanbanBoard } from '../components/kanban/KanbanBoard';
 import { KanbanHeader } from '../components/layout/KanbanHeader';
+import * as boardService from '../services/boards';
 import styles from './KanbanPage.module.css';
 
-const PUBLIC_BOARD_ID = 8; 
-
 export function KanbanPage() {
   const { isLoading, fetchBoardData, board } = useBoard();
   const { boardId } = useParams<{ boardId: string }>();
 
   useEffect(() => {
-    const idToFetch = boardId ? parseInt(boardId, 10) : PUBLIC_BOARD_ID;
-    const isPrivate = !!boardId; 
-
-    fetchBoardData(idToFetch, isPrivate);
+    if (boardId) {
+      fetchBoardData(parseInt(boardId, 10), true);
+      return;
+    }
+    boardService.getDefaultPublicBoard()
+      .then(defaultBoard => fetchBoardData(defaultBoard.id, false))
+      .catch(error => toast.error(error.message));
 
   }, [boardId, fetchBoardData]); 
 
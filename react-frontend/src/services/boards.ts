@@ -1,19 +1,56 @@
 import { api } from '../api/api';
-import { Board, User } from '../types/kanban';
+import { Board, BoardTemplate, Column, Label, User } from '../types/kanban';
 
-export async function getPublicBoards(): Promise<Board[]> {
-    const response = await api('/boards/public');
+export interface PublicBoardsPage {
+    boards: Board[];
+    total: number;
+    page: number;
+    page_size: number;
+}
+
+export async function getPublicBoardsPage(params: { page?: number; pageSize?: number; sort?: 'created_at' | 'updated_at' | 'title'; order?: 'asc' | 'desc'; team?: string } = {}): Promise<PublicBoardsPage> {
+    const query = new URLSearchParams();
+    if (params.page) query.set('page', String(params.page));
+    if (params.pageSize) query.set('page_size', String(params.pageSize));
+    if (params.sort) query.set('sort', params.sort);
+    if (params.order) query.set('order', params.order);
+    if (params.team) query.set('team', params.team);
+    const qs = query.toString();
+    const response = await api(`/boards/public${qs ? `?${qs}` : ''}`);
     if (!response.ok) throw new Error('Falha ao buscar quadros públicos');
     return response.json();
 }
 
+export async function getPublicBoards(): Promise<Board[]> {
+    const page = await getPublicBoardsPage();
+    return page.boards;
+}
+
+export async function getDefaultPublicBoard(team?: string): Promise<Board> {
+    const response = await api(`/boards/public/default${team ? `?team=${encodeURIComponent(team)}` : ''}`);
+    if (!response.ok) throw new Error('Nenhum quadro público encontrado');
+    return response.json();
+}
+
+export async function getBoardFull(boardId: number): Promise<{ board: Board; labels: Label[]; columns: Column[] }> {
+    const response = await api(`/boards/${boardId}/full`);
+    if (!response.ok) throw new Error(`Quadro com ID ${boardId} não encontrado.`);
+    return response.json();
+}
+
+export async function getBoard(boardId: number): Promise<Board> {
+    const response = await api(`/boards/${boardId}`);
+    if (!response.ok) throw new Error(`Quadro com ID ${boardId} não encontrado.`);
+    return response.json();
+}
+
 export async function getPrivateBoards(): Promise<Board[]> {
     const response = await api('/boards/private');
     if (!response.ok) throw new Error('Falha ao buscar quadros privados');
     return response.json();
 }
 
-export async function createBoard(boardData: Partial<Board>): Promise<Board> {
+export async function createBoard(boardData: Partial<Board> & { template_id?: number }): Promise<Board> {
     const response = await api('/boards', {
         method: 'POST',
         body: JSON.stringify(boardData),
@@ -22,6 +59,30 @@ export async function createBoard(boardData: Partial<Board>): Promise<Board> {
     return response.json();
 }
 
+export async function getBoardTemplates(): Promise<BoardTemplate[]> {
+    const response = await api('/templates');
+    if (!response.ok) throw new Error('Falha ao buscar templates');
+    return response.json();
+}
+
+export async function cloneBoard(boardId: number, options: { title?: string; include_cards?: boolean } = {}): Promise<Board> {
+    const response = await api(`/boards/${boardId}/clone`, {
+        method: 'POST',
+        body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Falha ao clonar quadro');
+    return response.json();
+}
+
+export async function saveBoardAsTemplate(boardId: number, options: { name?: string; description?: string; is_shared?: boolean; include_cards?: boolean }): Promise<BoardTemplate> {
+    const response = await api(`/boards/${boardId}/save-as-template`, {
+        method: 'POST',
+        body: JSON.stringify(options),
+    });
+    if (!response.ok) throw new Error('Falha ao salvar template');
+    return response.json();
+}
+
 export async function deleteBoard(boardId: number): Promise<void> {
     const response = await api(`/boards/${boardId}`, {
         method: 'DELETE',
@@ -1,8 +1,8 @@
-import { api } from '../api/api';
+import { api, apiErrorMessage } from '../api/api';
 import type { Card } from '../types/kanban';
 
-export async function getCardsForColumn(columnId: number): Promise<Card[]> {
-    const response = await api(`/columns/${columnId}/cards`);
+export async function getCardsForColumn(columnId: number, includeArchived = false): Promise<Card[]> {
+    const response = await api(`/columns/${columnId}/cards${includeArchived ? '?include_archived=true' : ''}`);
     if (!response.ok) throw new Error('Falha ao buscar os cards da coluna.');
     return response.json();
 }
@@ -13,18 +13,33 @@ export async function createCard(columnId: number, cardData: Partial<Card>): Pro
         body: JSON.stringify(cardData)
     });
     if (!response.ok) {
-        const errorData = await response.json().catch(() => ({ error: 'Falha ao criar o card.' }));
-        throw new Error(errorData.error);
+        throw new Error(await apiErrorMessage(response, 'Falha ao criar o card.'));
     }
     return response.json();
 }
 
-export async function updateCard(cardId: number, cardData: Partial<Card>): Promise<Card> {
+// só os campos alterados; ausente = mantém, null = limpa (JSON Merge Patch)
+export type CardPatch = Partial<Pick<Card, 'title' | 'description' | 'assigned_to' | 'priority' | 'due_date' | 'completed_at'>>;
+
+// 409: o card mudou desde a versão enviada; current é a cópia atual do servidor
+export class CardConflictError extends Error {
+    constructor(message: string, public current: Card) {
+        super(message);
+        this.name = 'CardConflictError';
+    }
+}
+
+export async function patchCard(cardId: number, changes: CardPatch, version?: number): Promise<Card> {
     const response = await api(`/cards/${cardId}`, {
-        method: 'PUT',
-        body: JSON.stringify(cardData)
+        method: 'PATCH',
+        headers: version ? { 'If-Match': `"${version}"` } : undefined,
+        body: JSON.stringify(changes)
     });
-    if (!response.ok) throw new Error('Falha ao atualizar o card.');
+    if (response.status === 409) {
+        const data = await response.json();
+        throw new CardConflictError(data?.error?.message || 'O card foi alterado por outra pessoa.', data.current);
+    }
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar o card.'));
     return response.json();
 }
 
@@ -43,4 +58,90 @@ export async function moveCard(cardId: number, newColumnId: number, newPosition:
         })
     });
     if (!response.ok) throw new Error('Falha ao mover o card.');
-}
\ No newline at end of file
+}
+export async function archiveCard(cardId: number): Promise<Card> {
+    const response = await api(`/cards/${cardId}/archive`, { method: 'POST' });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao arquivar o card.'));
+    return response.json();
+}
+
+export async function unarchiveCard(cardId: number): Promise<Card> {
+    const response = await api(`/cards/${cardId}/unarchive`, { method: 'POST' });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao desarquivar o card.'));
+    return response.json();
+}
+
+export async function archiveColumnCards(columnId: number, olderThanDays: number): Promise<{ archived: number; card_ids: number[] }> {
+    const response = await api(`/columns/${columnId}/archive`, {
+        method: 'POST',
+        body: JSON.stringify({ older_than_days: olderThanDays })
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao arquivar os cards.'));
+    return response.json();
+}
+
+export type BulkOperation = 'move' | 'assign' | 'priority' | 'due_date' | 'archive' | 'delete';
+
+export interface BulkRequest {
+    card_ids: number[];
+    operation: BulkOperation;
+    column_id?: number;
+    allow_cross_board?: boolean;
+    assigned_to?: string;
+    priority?: Card['priority'];
+    due_date?: string | null;
+}
+
+export async function bulkUpdateCards(request: BulkRequest): Promise<{ operation: BulkOperation; cards: Card[]; removed_card_ids: number[] }> {
+    const response = await api('/cards/bulk', {
+        method: 'POST',
+        body: JSON.stringify(request)
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao aplicar a operação em lote.'));
+    return response.json();
+}
+
+export interface CardSearchParams {
+    q?: string;
+    board_id?: number;
+    column_id?: number;
+    assigned_to?: string;
+    assignee_id?: string;
+    priority?: Card['priority'];
+    due_from?: string;
+    due_to?: string;
+    created_from?: string;
+    created_to?: string;
+    completed_from?: string;
+    completed_to?: string;
+    overdue?: boolean;
+    archived?: 'include' | 'exclude' | 'only';
+    limit?: number;
+    cursor?: string;
+}
+
+export interface CardSearchResult extends Card {
+    board_id: number;
+    board_title: string;
+    column_title: string;
+    rank?: number;
+}
+
+export async function searchCards(params: CardSearchParams): Promise<{ cards: CardSearchResult[]; next_cursor: string | null }> {
+    const query = new URLSearchParams();
+    Object.entries(params).forEach(([key, value]) => {
+        if (value !== undefined && value !== '' && value !== false) query.set(key, String(value));
+    });
+    const response = await api(`/cards/search?${query}`);
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao buscar os cards.'));
+    return response.json();
+}
+
+export async function setCardAssignees(cardId: number, userIds: string[]): Promise<Card> {
+    const response = await api(`/cards/${cardId}/assignees`, {
+        method: 'PUT',
+        body: JSON.stringify({ user_ids: userIds })
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar os responsáveis.'));
+    return response.json();
+}
@@ -0,0 +1,31 @@
+import { api, apiErrorMessage } from '../api/api';
+import type { CardComment, CommentSectionName } from '../types/kanban';
+
+export async function getCardComments(cardId: number): Promise<CardComment[]> {
+    const response = await api(`/cards/${cardId}/comments`);
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao buscar os comentários.'));
+    return response.json();
+}
+
+export async function createCardComment(cardId: number, text: string, section: CommentSectionName | null): Promise<CardComment> {
+    const response = await api(`/cards/${cardId}/comments`, {
+        method: 'POST',
+        body: JSON.stringify({ text, section })
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao criar o comentário.'));
+    return response.json();
+}
+
+export async function updateCardComment(cardId: number, commentId: number, text: string): Promise<CardComment> {
+    const response = await api(`/cards/${cardId}/comments/${commentId}`, {
+        method: 'PUT',
+        body: JSON.stringify({ text })
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar o comentário.'));
+    return response.json();
+}
+
+export async function deleteCardComment(cardId: number, commentId: number): Promise<void> {
+    const response = await api(`/cards/${cardId}/comments/${commentId}`, { method: 'DELETE' });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao excluir o comentário.'));
+}
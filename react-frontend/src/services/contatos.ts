@@ -1,5 +1,5 @@
 import toast from 'react-hot-toast';
-import { api } from '../api/api';
+import { api, apiErrorMessage } from '../api/api';
 import { ClienteSinalAlto, ContatoStatus } from '../types/sinal';
 
 // URLs da API do Marques
@@ -67,10 +67,8 @@ export async function setContatoStatus(payload: {
         method: 'POST',
         body: JSON.stringify(payload)
     });
-     if (!response.ok) {
-        const errorData = await response.json().catch(() => ({ error: 'Erro desconhecido' }));
-        console.error("Erro do servidor ao salvar status:", errorData);
-        throw new Error(errorData.error || 'Falha ao salvar o status do contato');
+    if (!response.ok) {
+        throw new Error(await apiErrorMessage(response, 'Falha ao salvar o status do contato'));
     }
     return response.json();
 }
@@ -81,9 +79,7 @@ export async function assignContato(contatoId: string): Promise<any> {
         body: JSON.stringify({ contato_id: contatoId })
     });
     if (!response.ok) {
-        const errorData = await response.json().catch(() => ({ error: 'Erro desconhecido' }));
-        console.error("Erro do servidor ao assumir tarefa:", errorData);
-        throw new Error(errorData.error || 'Falha ao assumir a tarefa');
+        throw new Error(await apiErrorMessage(response, 'Falha ao assumir a tarefa'));
     }
     return response.json();
 }
@@ -94,9 +90,7 @@ export async function unassignContato(contatoId: string): Promise<any> {
         body: JSON.stringify({ contato_id: contatoId })
     });
     if (!response.ok) {
-        const errorData = await response.json().catch(() => ({ error: 'Erro desconhecido' }));
-        console.error("Erro do servidor ao desassociar tarefa:", errorData);
-        throw new Error(errorData.error || 'Falha ao desassociar a tarefa');
+        throw new Error(await apiErrorMessage(response, 'Falha ao desassociar a tarefa'));
     }
     return response.json();
 }
@@ -108,9 +102,7 @@ export async function adminAssignContato(contatoId: string, assigneeId: string):
         body: JSON.stringify({ contato_id: contatoId, assignee_id: assigneeId })
     });
     if (!response.ok) {
-        const errorData = await response.json().catch(() => ({ error: 'Erro desconhecido' }));
-        console.error("Erro do servidor ao atribuir tarefa (admin):", errorData);
-        throw new Error(errorData.error || 'Falha ao atribuir a tarefa');
+        throw new Error(await apiErrorMessage(response, 'Falha ao atribuir a tarefa'));
     }
     return response.json();
 }
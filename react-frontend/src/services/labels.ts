@@ -0,0 +1,40 @@
+import { api, apiErrorMessage } from '../api/api';
+import type { Label } from '../types/kanban';
+
+export async function getBoardLabels(boardId: number): Promise<Label[]> {
+    const response = await api(`/boards/${boardId}/labels`);
+    if (!response.ok) throw new Error('Falha ao buscar as etiquetas');
+    return response.json();
+}
+
+export async function createLabel(boardId: number, label: Pick<Label, 'name' | 'color'>): Promise<Label> {
+    const response = await api(`/boards/${boardId}/labels`, {
+        method: 'POST',
+        body: JSON.stringify(label)
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao criar a etiqueta.'));
+    return response.json();
+}
+
+export async function updateLabel(boardId: number, labelId: number, label: Pick<Label, 'name' | 'color'>): Promise<Label> {
+    const response = await api(`/boards/${boardId}/labels/${labelId}`, {
+        method: 'PUT',
+        body: JSON.stringify(label)
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar a etiqueta.'));
+    return response.json();
+}
+
+export async function deleteLabel(boardId: number, labelId: number): Promise<void> {
+    const response = await api(`/boards/${boardId}/labels/${labelId}`, { method: 'DELETE' });
+    if (!response.ok) throw new Error('Falha ao excluir a etiqueta');
+}
+
+export async function setCardLabels(cardId: number, labelIds: number[]): Promise<Label[]> {
+    const response = await api(`/cards/${cardId}/labels`, {
+        method: 'PUT',
+        body: JSON.stringify({ label_ids: labelIds })
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar as etiquetas do card.'));
+    return response.json();
+}
@@ -0,0 +1,15 @@
+import { api } from '../api/api';
+import { TrashItem } from '../types/kanban';
+
+export async function getTrash(): Promise<TrashItem[]> {
+    const response = await api('/trash');
+    if (!response.ok) throw new Error('Falha ao buscar a lixeira');
+    return response.json();
+}
+
+export async function restoreTrashItem(item: Pick<TrashItem, 'kind' | 'id'>): Promise<void> {
+    const response = await api(`/trash/${item.kind}s/${item.id}/restore`, {
+        method: 'POST',
+    });
+    if (!response.ok) throw new Error('Falha ao restaurar item');
+}
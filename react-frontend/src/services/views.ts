@@ -0,0 +1,44 @@
+import { api, apiErrorMessage } from '../api/api';
+import type { SavedView, ViewGroup } from '../types/kanban';
+import type { CardSearchResult } from './cards';
+
+export async function getViews(boardId?: number): Promise<SavedView[]> {
+    const response = await api(`/views${boardId ? `?board_id=${boardId}` : ''}`);
+    if (!response.ok) throw new Error('Falha ao buscar as visões');
+    return response.json();
+}
+
+export async function createView(view: Partial<SavedView>): Promise<SavedView> {
+    const response = await api('/views', {
+        method: 'POST',
+        body: JSON.stringify(view)
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao criar a visão.'));
+    return response.json();
+}
+
+export async function updateView(viewId: number, view: Partial<SavedView>): Promise<SavedView> {
+    const response = await api(`/views/${viewId}`, {
+        method: 'PUT',
+        body: JSON.stringify(view)
+    });
+    if (!response.ok) throw new Error(await apiErrorMessage(response, 'Falha ao atualizar a visão.'));
+    return response.json();
+}
+
+export async function deleteView(viewId: number): Promise<void> {
+    const response = await api(`/views/${viewId}`, { method: 'DELETE' });
+    if (!response.ok) throw new Error('Falha ao excluir a visão');
+}
+
+export async function getViewCards(viewId: number, cursor?: string): Promise<{ view: SavedView; cards: CardSearchResult[]; next_cursor: string | null }> {
+    const response = await api(`/views/${viewId}/cards${cursor ? `?cursor=${encodeURIComponent(cursor)}` : ''}`);
+    if (!response.ok) throw new Error('Falha ao executar a visão');
+    return response.json();
+}
+
+export async function getViewBoard(viewId: number, groupBy?: SavedView['group_by']): Promise<{ view: SavedView; group_by: string; groups: ViewGroup[]; truncated: boolean }> {
+    const response = await api(`/views/${viewId}/board${groupBy ? `?group_by=${groupBy}` : ''}`);
+    if (!response.ok) throw new Error('Falha ao montar o quadro da visão');
+    return response.json();
+}
@@ -17,6 +17,28 @@ export interface Board {
   owner_name?: string;
   is_public: boolean;
   color: string;
+  team?: string | null;
+  is_default: boolean;
+  created_at: string;
+  updated_at: string;
+  version?: number;
+}
+
+export interface TemplateColumn {
+  title: string;
+  color: string;
+  cards?: { title: string; description?: string; priority?: Card['priority'] }[];
+}
+
+export interface BoardTemplate {
+  id: number;
+  name: string;
+  description: string;
+  color: string;
+  owner_id?: string;
+  is_shared: boolean;
+  columns: TemplateColumn[];
+  labels: { name: string; color: string }[];
   created_at: string;
   updated_at: string;
 }
@@ -42,12 +64,84 @@ export interface Card {
   created_at: string;
   updated_at: string;
   completed_at: string | null;
+  archived_at?: string | null;
+  version?: number;
+  labels?: Label[];
+  assignees?: UserSummary[];
 }
 
-export interface Comment {
-    text: string;
+export interface UserSummary {
+  id: string;
+  name: string;
+  avatar: string;
+}
+
+export interface Label {
+  id: number;
+  board_id: number;
+  name: string;
+  color: string;
+  created_at: string;
+}
+
+export interface TrashItem {
+  kind: 'board' | 'column' | 'card';
+  id: number;
+  title: string;
+  board_id: number;
+  board_title: string;
+  column_id?: number;
+  deleted_at: string;
+  deleted_by?: string;
+  purge_at: string;
+}
+
+export interface SavedView {
+  id: number;
+  name: string;
+  owner_id: string;
+  board_id?: number;
+  is_shared: boolean;
+  filters: {
+    q?: string;
+    board_id?: number;
+    column_id?: number;
+    assigned_to?: string;
+    assignee_id?: string;
+    assigned_to_me?: boolean;
+    priority?: Card['priority'];
+    due_from?: string;
+    due_to?: string;
+    due_within_days?: number;
+    created_from?: string;
+    created_to?: string;
+    completed_from?: string;
+    completed_to?: string;
+    overdue?: boolean;
+    archived?: 'include' | 'exclude' | 'only';
+  };
+  group_by?: 'column' | 'assignee' | 'priority';
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ViewGroup {
+  key: string;
+  title: string;
+  cards: (Card & { board_id: number; board_title: string; column_title: string })[];
+}
+
+export type CommentSectionName = 'observacoes' | 'tentativas' | 'resolucao';
+
+export interface CardComment {
+    id: number;
+    card_id: number;
+    author_id?: string;
     author: string;
-    timestamp: string;
+    section?: CommentSectionName;
+    text: string;
+    created_at: string;
+    updated_at: string;
 }
 
 export interface Notification {
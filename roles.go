@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// papel de um usuário no board; o dono vem de boards.owner_id, os demais de board_memberships.role
+type BoardRole string
+
+const (
+	RoleViewer    BoardRole = "viewer"
+	RoleCommenter BoardRole = "commenter"
+	RoleEditor    BoardRole = "editor"
+	RoleOwner     BoardRole = "owner"
+)
+
+// papéis atribuíveis a membros e convites
+var memberRoles = []string{string(RoleEditor), string(RoleCommenter), string(RoleViewer)}
+
+var roleRank = map[BoardRole]int{
+	RoleViewer:    1,
+	RoleCommenter: 2,
+	RoleEditor:    3,
+	RoleOwner:     4,
+}
+
+func (r BoardRole) AtLeast(min BoardRole) bool {
+	return roleRank[r] >= roleRank[min]
+}
+
+// papel do usuário no board; vazio = sem acesso
+func (app *App) boardRole(userID string, boardID int) (BoardRole, error) {
+	return boardRoleIn(context.Background(), app.db, userID, boardID)
+}
+
+// papel lido pela transação, que enxerga boards e membros ainda não confirmados
+func boardRoleIn(ctx context.Context, q rowQuerier, userID string, boardID int) (BoardRole, error) {
+	var ownerID string
+	var isPublic bool
+	var memberRole *string
+	err := q.QueryRow(ctx, `
+		SELECT b.owner_id, b.is_public, bm.role
+		FROM boards b
+		LEFT JOIN board_memberships bm ON bm.board_id = b.id AND bm.user_id = $2
+		WHERE b.id = $1 AND b.deleted_at IS NULL`, boardID, userID).Scan(&ownerID, &isPublic, &memberRole)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return effectiveBoardRole(userID, ownerID, isPublic, memberRole), nil
+}
+
+// dono > maior entre o papel de membro e o padrão do quadro público, para que
+// um membro nunca tenha menos acesso que um usuário qualquer
+func effectiveBoardRole(userID, ownerID string, isPublic bool, memberRole *string) BoardRole {
+	if ownerID == userID {
+		return RoleOwner
+	}
+	var role BoardRole
+	if memberRole != nil {
+		role = BoardRole(*memberRole)
+	}
+	// quadros públicos continuam editáveis por qualquer usuário autenticado
+	if isPublic && !role.AtLeast(RoleEditor) {
+		role = RoleEditor
+	}
+	return role
+}
+
+// endpoint alterar papel de membro
+func (app *App) updateMemberRole(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	memberID := c.Params("memberId")
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		Role string `json:"role"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	var v Validator
+	v.OneOf("role", payload.Role, memberRoles)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(context.Background())
+
+	tag, err := tx.Exec(context.Background(),
+		"UPDATE board_memberships SET role = $1 WHERE board_id = $2 AND user_id = $3", payload.Role, boardID, memberID)
+	if err != nil {
+		log.Printf("Erro ao alterar papel do membro %s no board %d: %v", memberID, boardID, err)
+		return internalError(c, "Erro ao alterar papel do membro")
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound(c, "Membro não encontrado neste quadro")
+	}
+
+	var boardTitle string
+	if err := tx.QueryRow(context.Background(), "SELECT title FROM boards WHERE id = $1", boardID).Scan(&boardTitle); err != nil {
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	ownerName := app.getDisplayName(context.Background(), tx, userID)
+	if err := app.createNotification(tx, Notification{
+		UserID:         memberID,
+		Type:           "board_role_changed",
+		Message:        fmt.Sprintf("%s alterou seu papel no quadro '%s' para %s.", ownerName, boardTitle, payload.Role),
+		RelatedBoardID: &boardID,
+	}); err != nil {
+		log.Printf("Erro ao criar notificação na DB: %v", err)
+		return internalError(c, "Erro ao criar notificação")
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return internalError(c, "Erro ao confirmar alteração")
+	}
+
+	app.broadcast(boardID, WsMessage{
+		Type:     "MEMBER_ROLE_CHANGED",
+		SenderID: userID,
+		Payload:  fiber.Map{"user_id": memberID, "role": payload.Role},
+	})
+	return c.JSON(fiber.Map{"user_id": memberID, "role": payload.Role})
+}
+
+// endpoint transferir propriedade do board (dono atual ou admin)
+func (app *App) transferBoardOwnership(c *fiber.Ctx) error {
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do quadro inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	var payload struct {
+		NewOwnerID string `json:"new_owner_id"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+	var v Validator
+	v.Required("new_owner_id", payload.NewOwnerID, maxShortTextLength)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	tx, err := app.db.Begin(context.Background())
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(context.Background())
+
+	var ownerID, boardTitle string
+	err = tx.QueryRow(context.Background(),
+		"SELECT owner_id, title FROM boards WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", boardID).Scan(&ownerID, &boardTitle)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Quadro não encontrado")
+		}
+		return internalError(c, "Erro ao verificar o quadro")
+	}
+	if ownerID != userID {
+		isAdmin, err := app.isUserAdmin(userID)
+		if err != nil || !isAdmin {
+			return errorResponse(c, fiber.StatusForbidden, "forbidden", "Apenas o dono do quadro ou um administrador pode transferir a propriedade.", nil)
+		}
+	}
+	if payload.NewOwnerID == ownerID {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "new_owner_id", Code: "same_owner", Message: "o usuário já é o dono do quadro"}}})
+	}
+	var exists bool
+	err = tx.QueryRow(context.Background(), "SELECT EXISTS (SELECT 1 FROM auth.users WHERE id::text = $1)", payload.NewOwnerID).Scan(&exists)
+	if err != nil {
+		return internalError(c, "Erro ao verificar o usuário")
+	}
+	if !exists {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "new_owner_id", Code: "not_found", Message: "usuário não encontrado"}}})
+	}
+
+	var version int
+	if err := tx.QueryRow(context.Background(),
+		"UPDATE boards SET owner_id = $1, updated_at = NOW(), version = version + 1 WHERE id = $2 RETURNING version",
+		payload.NewOwnerID, boardID).Scan(&version); err != nil {
+		log.Printf("Erro ao transferir o board %d: %v", boardID, err)
+		return internalError(c, "Erro ao transferir a propriedade")
+	}
+	// o novo dono deixa de ser membro comum e o antigo passa a editor
+	if _, err := tx.Exec(context.Background(),
+		"DELETE FROM board_memberships WHERE board_id = $1 AND user_id = $2", boardID, payload.NewOwnerID); err != nil {
+		return internalError(c, "Erro ao atualizar membros")
+	}
+	if _, err := tx.Exec(context.Background(), `
+		INSERT INTO board_memberships (board_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (board_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		boardID, ownerID, RoleEditor); err != nil {
+		return internalError(c, "Erro ao atualizar membros")
+	}
+
+	actorName := app.getDisplayName(context.Background(), tx, userID)
+	newOwnerName := app.getDisplayName(context.Background(), tx, payload.NewOwnerID)
+	for _, n := range []Notification{{
+		UserID:         payload.NewOwnerID,
+		Type:           "board_ownership_received",
+		Message:        fmt.Sprintf("%s transferiu para você a propriedade do quadro '%s'.", actorName, boardTitle),
+		RelatedBoardID: &boardID,
+	}, {
+		UserID:         ownerID,
+		Type:           "board_ownership_transferred",
+		Message:        fmt.Sprintf("A propriedade do quadro '%s' foi transferida para %s. Você continua como editor.", boardTitle, newOwnerName),
+		RelatedBoardID: &boardID,
+	}} {
+		if err := app.createNotification(tx, n); err != nil {
+			log.Printf("Erro ao criar notificação na DB: %v", err)
+			return internalError(c, "Erro ao criar notificação")
+		}
+	}
+
+	if err := tx.Commit(context.Background()); err != nil {
+		return internalError(c, "Erro ao confirmar transferência")
+	}
+
+	app.broadcast(boardID, WsMessage{
+		Type:     "BOARD_OWNER_CHANGED",
+		SenderID: userID,
+		Payload:  fiber.Map{"board_id": boardID, "owner_id": payload.NewOwnerID, "previous_owner_id": ownerID, "version": version},
+	})
+	setVersionETag(c, version)
+	return c.JSON(fiber.Map{"board_id": boardID, "owner_id": payload.NewOwnerID, "previous_owner_id": ownerID, "version": version})
+}
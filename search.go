@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 100
+)
+
+// filtro de arquivados na busca: por padrão a busca alcança os arquivados
+var searchArchivedModes = []string{"include", "exclude", "only"}
+
+// card encontrado, com o contexto de board e coluna
+type CardSearchResult struct {
+	Card
+	BoardID     int     `json:"board_id"`
+	BoardTitle  string  `json:"board_title"`
+	ColumnTitle string  `json:"column_title"`
+	Rank        float32 `json:"rank,omitempty"`
+}
+
+// posição da última linha da página anterior
+type searchCursor struct {
+	Rank      float32   `json:"r,omitempty"`
+	UpdatedAt time.Time `json:"u"`
+	ID        int       `json:"i"`
+}
+
+func encodeSearchCursor(cur searchCursor) string {
+	raw, _ := json.Marshal(cur)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeSearchCursor(value string) (searchCursor, error) {
+	var cur searchCursor
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cur, err
+	}
+	err = json.Unmarshal(raw, &cur)
+	return cur, err
+}
+
+// filtros da busca lidos da query string
+type CardSearchFilter struct {
+	Query         string     `json:"q,omitempty"`
+	BoardID       int        `json:"board_id,omitempty"`
+	ColumnID      int        `json:"column_id,omitempty"`
+	AssignedTo    string     `json:"assigned_to,omitempty"`
+	AssigneeID    string     `json:"assignee_id,omitempty"`
+	Priority      string     `json:"priority,omitempty"`
+	DueFrom       *time.Time `json:"due_from,omitempty"`
+	DueTo         *time.Time `json:"due_to,omitempty"`
+	CreatedFrom   *time.Time `json:"created_from,omitempty"`
+	CreatedTo     *time.Time `json:"created_to,omitempty"`
+	CompletedFrom *time.Time `json:"completed_from,omitempty"`
+	CompletedTo   *time.Time `json:"completed_to,omitempty"`
+	Overdue       bool       `json:"overdue,omitempty"`
+	Archived      string     `json:"archived,omitempty"`
+}
+
+// data aceita como YYYY-MM-DD ou RFC3339
+func parseFilterTime(value string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func parseCardSearchFilter(c *fiber.Ctx) (CardSearchFilter, error) {
+	f := CardSearchFilter{
+		Query:      strings.TrimSpace(c.Query("q")),
+		AssignedTo: c.Query("assigned_to"),
+		AssigneeID: c.Query("assignee_id"),
+		Priority:   c.Query("priority"),
+		Overdue:    c.QueryBool("overdue"),
+		Archived:   c.Query("archived", "include"),
+	}
+	var v Validator
+	for name, dst := range map[string]*int{"board_id": &f.BoardID, "column_id": &f.ColumnID} {
+		if raw := c.Query(name); raw != "" {
+			id, err := strconv.Atoi(raw)
+			if err != nil || id <= 0 {
+				v.Add(name, "invalid_id", "ID inválido")
+				continue
+			}
+			*dst = id
+		}
+	}
+	validateSearchFilter(&v, "", f)
+	dates := []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"due_from", &f.DueFrom}, {"due_to", &f.DueTo},
+		{"created_from", &f.CreatedFrom}, {"created_to", &f.CreatedTo},
+		{"completed_from", &f.CompletedFrom}, {"completed_to", &f.CompletedTo},
+	}
+	for _, d := range dates {
+		if raw := c.Query(d.name); raw != "" {
+			t, err := parseFilterTime(raw)
+			if err != nil {
+				v.Add(d.name, "invalid_date", "data inválida, use YYYY-MM-DD ou RFC3339")
+				continue
+			}
+			*d.dst = t
+		}
+	}
+	return f, v.Err()
+}
+
+// boards acessíveis ao usuário: dono, membro ou público (mesma regra de boardRole)
+func boardAccessCondition(alias, userParam string) string {
+	return fmt.Sprintf(`(%[1]s.owner_id = %[2]s OR %[1]s.is_public OR EXISTS (
+		SELECT 1 FROM board_memberships bm WHERE bm.board_id = %[1]s.id AND bm.user_id = %[2]s))`, alias, userParam)
+}
+
+// validação comum à busca e às visões salvas
+func validateSearchFilter(v *Validator, prefix string, f CardSearchFilter) {
+	v.MaxLength(prefix+"q", f.Query, maxShortTextLength)
+	v.MaxLength(prefix+"assigned_to", f.AssignedTo, maxShortTextLength)
+	v.MaxLength(prefix+"assignee_id", f.AssigneeID, maxShortTextLength)
+	if f.BoardID < 0 {
+		v.Add(prefix+"board_id", "invalid_id", "ID inválido")
+	}
+	if f.ColumnID < 0 {
+		v.Add(prefix+"column_id", "invalid_id", "ID inválido")
+	}
+	if f.Priority != "" {
+		v.OneOf(prefix+"priority", f.Priority, cardPriorities)
+	}
+	if f.Archived != "" {
+		v.OneOf(prefix+"archived", f.Archived, searchArchivedModes)
+	}
+}
+
+// monta o WHERE da busca; $1 é sempre o usuário
+type searchQuery struct {
+	conds []string
+	args  []interface{}
+}
+
+func (q *searchQuery) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *searchQuery) where(format string, values ...interface{}) {
+	placeholders := make([]interface{}, len(values))
+	for i, v := range values {
+		placeholders[i] = q.arg(v)
+	}
+	q.conds = append(q.conds, fmt.Sprintf(format, placeholders...))
+}
+
+// executar a busca com permissões do usuário; devolve a página e o próximo cursor
+func (app *App) searchCards(ctx context.Context, userID string, f CardSearchFilter, cursor *searchCursor, limit int) ([]CardSearchResult, string, error) {
+	q := &searchQuery{}
+	user := q.arg(userID)
+	q.conds = append(q.conds,
+		"ca.deleted_at IS NULL", "col.deleted_at IS NULL", "b.deleted_at IS NULL",
+		boardAccessCondition("b", user))
+
+	rank := "0::real"
+	if f.Query != "" {
+		tsquery := fmt.Sprintf("websearch_to_tsquery('portuguese', %s)", q.arg(f.Query))
+		rank = fmt.Sprintf("ts_rank(ca.search_vector, %s)", tsquery)
+		q.conds = append(q.conds, fmt.Sprintf(`(ca.search_vector @@ %[1]s OR EXISTS (
+			SELECT 1 FROM card_comments cm WHERE cm.card_id = ca.id AND to_tsvector('portuguese', cm.content) @@ %[1]s))`, tsquery))
+	}
+	if f.BoardID != 0 {
+		q.where("col.board_id = %s", f.BoardID)
+	}
+	if f.ColumnID != 0 {
+		q.where("ca.column_id = %s", f.ColumnID)
+	}
+	if f.AssignedTo != "" {
+		q.where("ca.assigned_to = %s", f.AssignedTo)
+	}
+	if f.AssigneeID != "" {
+		q.where("EXISTS (SELECT 1 FROM card_assignees cs WHERE cs.card_id = ca.id AND cs.user_id::text = %s)", f.AssigneeID)
+	}
+	if f.Priority != "" {
+		q.where("ca.priority = %s", f.Priority)
+	}
+	if f.DueFrom != nil {
+		q.where("ca.due_date >= %s", *f.DueFrom)
+	}
+	if f.DueTo != nil {
+		q.where("ca.due_date <= %s", *f.DueTo)
+	}
+	if f.CreatedFrom != nil {
+		q.where("ca.created_at >= %s", *f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		q.where("ca.created_at <= %s", *f.CreatedTo)
+	}
+	if f.CompletedFrom != nil {
+		q.where("ca.completed_at >= %s", *f.CompletedFrom)
+	}
+	if f.CompletedTo != nil {
+		q.where("ca.completed_at <= %s", *f.CompletedTo)
+	}
+	if f.Overdue {
+		q.conds = append(q.conds, "ca.due_date < NOW() AND ca.completed_at IS NULL")
+	}
+	switch f.Archived {
+	case "exclude":
+		q.conds = append(q.conds, "ca.archived_at IS NULL")
+	case "only":
+		q.conds = append(q.conds, "ca.archived_at IS NOT NULL")
+	}
+
+	// com texto ordena por relevância, sem texto pelos mais recentes
+	order := "updated_at DESC, id DESC"
+	outer := ""
+	if f.Query != "" {
+		order = "rank DESC, id DESC"
+	}
+	if cursor != nil {
+		if f.Query != "" {
+			r, i := q.arg(cursor.Rank), q.arg(cursor.ID)
+			outer = fmt.Sprintf("WHERE (rank < %s::real OR (rank = %s::real AND id < %s))", r, r, i)
+		} else {
+			u, i := q.arg(cursor.UpdatedAt), q.arg(cursor.ID)
+			outer = fmt.Sprintf("WHERE (updated_at, id) < (%s, %s)", u, i)
+		}
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s, board_id, board_title, column_title, rank FROM (
+			SELECT ca.*, col.board_id, b.title AS board_title, col.title AS column_title, %s AS rank
+			FROM cards ca
+			JOIN columns col ON col.id = ca.column_id
+			JOIN boards b ON b.id = col.board_id
+			WHERE %s
+		) matched
+		%s
+		ORDER BY %s
+		LIMIT %s`, cardSelectColumns, rank, strings.Join(q.conds, " AND "), outer, order, q.arg(limit+1))
+
+	rows, err := app.db.Query(ctx, query, q.args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	results := make([]CardSearchResult, 0, limit)
+	for rows.Next() {
+		var r CardSearchResult
+		card := &r.Card
+		if err := rows.Scan(&card.ID, &card.ColumnID, &card.Title, &card.Description,
+			&card.AssignedTo, &card.Priority, &card.DueDate, &card.Position,
+			&card.CreatedAt, &card.UpdatedAt, &card.CompletedAt, &card.ArchivedAt, &card.Version,
+			&r.BoardID, &r.BoardTitle, &r.ColumnTitle, &r.Rank); err != nil {
+			return nil, "", err
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+	rows.Close()
+
+	next := ""
+	if len(results) > limit {
+		results = results[:limit]
+		last := results[limit-1]
+		next = encodeSearchCursor(searchCursor{Rank: last.Rank, UpdatedAt: last.UpdatedAt, ID: last.ID})
+	}
+	cards := make([]*Card, len(results))
+	for i := range results {
+		cards[i] = &results[i].Card
+	}
+	if err := app.attachCardDetails(ctx, cards); err != nil {
+		return nil, "", err
+	}
+	return results, next, nil
+}
+
+// endpoint busca de cards
+func (app *App) getCardSearch(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	filter, err := parseCardSearchFilter(c)
+	if err != nil {
+		return validationFailed(c, err)
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultSearchLimit)))
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+	var cursor *searchCursor
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeSearchCursor(raw)
+		if err != nil {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "cursor", Code: "invalid_cursor", Message: "cursor inválido"}}})
+		}
+		cursor = &cur
+	}
+
+	results, next, err := app.searchCards(context.Background(), userID, filter, cursor, limit)
+	if err != nil {
+		log.Printf("Erro na busca de cards: %v", err)
+		return internalError(c, "Erro ao buscar cards")
+	}
+	var nextCursor interface{}
+	if next != "" {
+		nextCursor = next
+	}
+	return c.JSON(fiber.Map{"cards": results, "next_cursor": nextCursor})
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// quantidade de eventos guardados por board para replay
+const boardEventRetention = 500
+
+// a limpeza do log roda a cada N eventos do board
+const boardEventPruneEvery = 50
+
+// atribuir seq ao evento e guardar no log do board
+func (app *App) appendBoardEvent(boardID int, message WsMessage) (int64, error) {
+	payload, err := json.Marshal(message.Payload)
+	if err != nil {
+		return 0, err
+	}
+	query := `
+		WITH s AS (
+			INSERT INTO board_sequences (board_id, last_seq) VALUES ($1, 1)
+			ON CONFLICT (board_id) DO UPDATE SET last_seq = board_sequences.last_seq + 1
+			RETURNING last_seq
+		)
+		INSERT INTO board_event_log (board_id, seq, type, sender_id, payload)
+		SELECT $1, last_seq, $2, NULLIF($3, ''), $4 FROM s
+		RETURNING seq`
+	var seq int64
+	err = app.db.QueryRow(context.Background(), query, boardID, message.Type, message.SenderID, payload).Scan(&seq)
+	if err != nil {
+		return 0, err
+	}
+	if seq%boardEventPruneEvery == 0 {
+		_, err = app.db.Exec(context.Background(),
+			"DELETE FROM board_event_log WHERE board_id = $1 AND seq <= $2", boardID, seq-boardEventRetention)
+		if err != nil {
+			return seq, fmt.Errorf("erro ao limpar log de eventos: %w", err)
+		}
+	}
+	return seq, nil
+}
+
+// último seq emitido para o board
+func (app *App) latestBoardSeq(boardID int) (int64, error) {
+	var seq int64
+	err := app.db.QueryRow(context.Background(),
+		"SELECT COALESCE((SELECT last_seq FROM board_sequences WHERE board_id = $1), 0)", boardID).Scan(&seq)
+	return seq, err
+}
+
+// enviar estado de sincronização e eventos perdidos desde ?since=; roda com o
+// cliente já registrado, então eventos ao vivo até o último seq enviado aqui são descartados
+func (app *App) replayBoardEvents(client *wsClient, sinceParam string) error {
+	latest, err := app.latestBoardSeq(client.boardID)
+	if err != nil {
+		return err
+	}
+	client.replayedSeq = latest
+	if sinceParam == "" {
+		return app.writeWsMessage(client, WsMessage{Type: "SYNC_STATE", Payload: map[string]int64{"latest_seq": latest}})
+	}
+
+	since, err := strconv.ParseInt(sinceParam, 10, 64)
+	if err != nil || since < 0 || since > latest {
+		return app.writeWsMessage(client, WsMessage{Type: "RESYNC_REQUIRED", Payload: map[string]int64{"latest_seq": latest}})
+	}
+	if since == latest {
+		return app.writeWsMessage(client, WsMessage{Type: "SYNC_STATE", Payload: map[string]int64{"latest_seq": latest}})
+	}
+
+	rows, err := app.db.Query(context.Background(), `
+		SELECT seq, type, COALESCE(sender_id, ''), payload
+		FROM board_event_log
+		WHERE board_id = $1 AND seq > $2
+		ORDER BY seq`, client.boardID, since)
+	if err != nil {
+		return err
+	}
+	missed := make([]WsMessage, 0)
+	for rows.Next() {
+		var msg WsMessage
+		var payload json.RawMessage
+		if err := rows.Scan(&msg.Seq, &msg.Type, &msg.SenderID, &payload); err != nil {
+			rows.Close()
+			return err
+		}
+		msg.Payload = payload
+		missed = append(missed, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// o log já foi podado além do ponto pedido
+	if len(missed) == 0 || missed[0].Seq != since+1 {
+		return app.writeWsMessage(client, WsMessage{Type: "RESYNC_REQUIRED", Payload: map[string]int64{"latest_seq": latest}})
+	}
+	for _, msg := range missed {
+		if err := app.writeWsMessage(client, msg); err != nil {
+			return err
+		}
+		client.replayedSeq = msg.Seq
+	}
+	return nil
+}
+
+func (app *App) writeWsMessage(client *wsClient, message WsMessage) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return client.writeDirect(data)
+}
@@ -0,0 +1,473 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// card inicial de um template
+type TemplateCard struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Priority    string `json:"priority,omitempty"`
+}
+
+// coluna de um template, com seus cards iniciais
+type TemplateColumn struct {
+	Title string         `json:"title"`
+	Color string         `json:"color"`
+	Cards []TemplateCard `json:"cards,omitempty"`
+}
+
+// etiqueta de um template
+type TemplateLabel struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// estrutura board template; colunas e etiquetas ficam em jsonb
+type BoardTemplate struct {
+	ID          int              `json:"id" db:"id"`
+	Name        string           `json:"name" db:"name"`
+	Description string           `json:"description" db:"description"`
+	Color       string           `json:"color" db:"color"`
+	OwnerID     *string          `json:"owner_id,omitempty" db:"owner_id"`
+	IsShared    bool             `json:"is_shared" db:"is_shared"`
+	Columns     []TemplateColumn `json:"columns" db:"columns"`
+	Labels      []TemplateLabel  `json:"labels" db:"labels"`
+	CreatedAt   time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time        `json:"updated_at" db:"updated_at"`
+}
+
+const templateSelectColumns = `id, name, COALESCE(description, '') as description, COALESCE(color, '') as color,
+	owner_id::text, is_shared, columns, labels, created_at, updated_at`
+
+func scanTemplate(row pgx.Row, t *BoardTemplate) error {
+	return row.Scan(&t.ID, &t.Name, &t.Description, &t.Color, &t.OwnerID, &t.IsShared, &t.Columns, &t.Labels, &t.CreatedAt, &t.UpdatedAt)
+}
+
+// colunas usadas quando o board é criado sem template
+var defaultBoardTemplate = BoardTemplate{
+	Name:        "Padrão",
+	Description: "A Fazer, Em Andamento e Concluído",
+	IsShared:    true,
+	Columns: []TemplateColumn{
+		{Title: "A Fazer", Color: "#58a6ff"},
+		{Title: "Em Andamento", Color: "#d29922"},
+		{Title: "Concluído", Color: "#3fb950"},
+	},
+	Labels: []TemplateLabel{},
+}
+
+func validateTemplate(t *BoardTemplate) error {
+	var v Validator
+	v.Required("name", t.Name, maxTitleLength)
+	v.MaxLength("description", t.Description, maxLongTextLength)
+	v.HexColor("color", t.Color, false)
+	if len(t.Columns) == 0 {
+		v.Add("columns", "required", "o template precisa de pelo menos uma coluna")
+	}
+	for i, col := range t.Columns {
+		prefix := fmt.Sprintf("columns[%d]", i)
+		v.Required(prefix+".title", col.Title, maxColumnTitleLength)
+		v.HexColor(prefix+".color", col.Color, false)
+		for j, card := range col.Cards {
+			cardPrefix := fmt.Sprintf("%s.cards[%d]", prefix, j)
+			v.Required(cardPrefix+".title", card.Title, maxTitleLength)
+			v.MaxLength(cardPrefix+".description", card.Description, maxLongTextLength)
+			if card.Priority != "" {
+				v.OneOf(cardPrefix+".priority", card.Priority, cardPriorities)
+			}
+		}
+	}
+	for i, label := range t.Labels {
+		prefix := fmt.Sprintf("labels[%d]", i)
+		v.Required(prefix+".name", label.Name, maxColumnTitleLength)
+		v.HexColor(prefix+".color", label.Color, true)
+	}
+	return v.Err()
+}
+
+// template visível ao usuário: dele, compartilhado ou do sistema
+func (app *App) fetchVisibleTemplate(ctx context.Context, q rowQuerier, templateID int, userID string) (BoardTemplate, error) {
+	var t BoardTemplate
+	err := scanTemplate(q.QueryRow(ctx, `SELECT `+templateSelectColumns+` FROM board_templates
+		WHERE id = $1 AND (owner_id IS NULL OR is_shared OR owner_id::text = $2)`, templateID, userID), &t)
+	return t, err
+}
+
+// criar colunas, cards iniciais e etiquetas do template no board
+func (app *App) applyTemplate(ctx context.Context, tx pgx.Tx, boardID int, userID string, t *BoardTemplate) error {
+	for _, label := range t.Labels {
+		_, err := tx.Exec(ctx, `INSERT INTO labels (board_id, name, color) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+			boardID, label.Name, label.Color)
+		if err != nil {
+			return fmt.Errorf("etiqueta '%s': %w", label.Name, err)
+		}
+	}
+	for i, col := range t.Columns {
+		var columnID int
+		err := tx.QueryRow(ctx, `INSERT INTO columns (board_id, title, position, color) VALUES ($1, $2, $3, $4) RETURNING id`,
+			boardID, col.Title, i, col.Color).Scan(&columnID)
+		if err != nil {
+			return fmt.Errorf("coluna '%s': %w", col.Title, err)
+		}
+		for j, card := range col.Cards {
+			priority := card.Priority
+			if priority == "" {
+				priority = "media"
+			}
+			var cardID int
+			err := tx.QueryRow(ctx, `INSERT INTO cards (column_id, title, description, priority, position)
+				VALUES ($1, $2, $3, $4, $5) RETURNING id`, columnID, card.Title, card.Description, priority, j+1).Scan(&cardID)
+			if err != nil {
+				return fmt.Errorf("card '%s': %w", card.Title, err)
+			}
+			if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventCreated, "", nil, fiber.Map{"title": card.Title, "column_id": columnID}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// montar um template a partir das colunas (e opcionalmente dos cards) de um board
+func (app *App) templateFromBoard(ctx context.Context, boardID int, includeCards bool) ([]TemplateColumn, error) {
+	rows, err := app.db.Query(ctx, `SELECT id, title, COALESCE(color, '') FROM columns WHERE board_id = $1 AND deleted_at IS NULL ORDER BY position`, boardID)
+	if err != nil {
+		return nil, err
+	}
+	var columnIDs []int
+	columns := make([]TemplateColumn, 0)
+	for rows.Next() {
+		var id int
+		var col TemplateColumn
+		if err := rows.Scan(&id, &col.Title, &col.Color); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columnIDs = append(columnIDs, id)
+		columns = append(columns, col)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if !includeCards {
+		return columns, nil
+	}
+	for i, columnID := range columnIDs {
+		rows, err := app.db.Query(ctx, `SELECT title, COALESCE(description, ''), COALESCE(priority, 'media')
+			FROM cards WHERE column_id = $1 AND deleted_at IS NULL AND archived_at IS NULL ORDER BY position`, columnID)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var card TemplateCard
+			if err := rows.Scan(&card.Title, &card.Description, &card.Priority); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			columns[i].Cards = append(columns[i].Cards, card)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+	return columns, nil
+}
+
+// endpoint listar templates visíveis
+func (app *App) getBoardTemplates(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	rows, err := app.db.Query(context.Background(), `SELECT `+templateSelectColumns+` FROM board_templates
+		WHERE owner_id IS NULL OR is_shared OR owner_id::text = $1
+		ORDER BY owner_id IS NOT NULL, name`, userID)
+	if err != nil {
+		return internalError(c, "Erro ao buscar templates")
+	}
+	defer rows.Close()
+	templates := make([]BoardTemplate, 0)
+	for rows.Next() {
+		var t BoardTemplate
+		if err := scanTemplate(rows, &t); err != nil {
+			return internalError(c, "Erro ao ler template")
+		}
+		templates = append(templates, t)
+	}
+	return c.JSON(templates)
+}
+
+// endpoint excluir template (apenas o autor)
+func (app *App) deleteBoardTemplate(c *fiber.Ctx) error {
+	templateID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do template inválido")
+	}
+	userID := c.Locals("userID").(string)
+	tag, err := app.db.Exec(context.Background(),
+		"DELETE FROM board_templates WHERE id = $1 AND owner_id::text = $2", templateID, userID)
+	if err != nil {
+		return internalError(c, "Erro ao excluir template")
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound(c, "Template não encontrado")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// endpoint salvar board como template
+func (app *App) saveBoardAsTemplate(c *fiber.Ctx) error {
+	boardID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		Name         string          `json:"name"`
+		Description  string          `json:"description"`
+		IsShared     bool            `json:"is_shared"`
+		IncludeCards bool            `json:"include_cards"`
+		Labels       []TemplateLabel `json:"labels"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+
+	ctx := context.Background()
+	var board Board
+	if err := scanBoard(app.db.QueryRow(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE id = $1", boardID), &board); err != nil {
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	// cards de board privado não vão para um template visível a todos
+	if payload.IsShared && payload.IncludeCards && !board.IsPublic {
+		return validationFailed(c, &ValidationError{Fields: []FieldError{{
+			Field: "is_shared", Code: "private_cards", Message: "templates compartilhados não podem levar cards de um quadro privado",
+		}}})
+	}
+	columns, err := app.templateFromBoard(ctx, boardID, payload.IncludeCards)
+	if err != nil {
+		log.Printf("Erro ao ler estrutura do board %d: %v", boardID, err)
+		return internalError(c, "Erro ao ler a estrutura do quadro")
+	}
+
+	t := BoardTemplate{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Color:       board.Color,
+		IsShared:    payload.IsShared,
+		Columns:     columns,
+		Labels:      payload.Labels,
+	}
+	if t.Name == "" {
+		t.Name = board.Title
+	}
+	// sem etiquetas no corpo, o template leva as do próprio board
+	if t.Labels == nil {
+		labels, err := app.loadBoardLabels(ctx, boardID)
+		if err != nil {
+			return internalError(c, "Erro ao buscar etiquetas")
+		}
+		t.Labels = make([]TemplateLabel, len(labels))
+		for i, l := range labels {
+			t.Labels[i] = TemplateLabel{Name: l.Name, Color: l.Color}
+		}
+	}
+	if err := validateTemplate(&t); err != nil {
+		return validationFailed(c, err)
+	}
+
+	err = scanTemplate(app.db.QueryRow(ctx, `
+		INSERT INTO board_templates (name, description, color, owner_id, is_shared, columns, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+templateSelectColumns, t.Name, t.Description, t.Color, userID, t.IsShared, t.Columns, t.Labels), &t)
+	if err != nil {
+		log.Printf("Erro ao salvar template do board %d: %v", boardID, err)
+		return internalError(c, "Erro ao salvar template")
+	}
+	return c.Status(201).JSON(t)
+}
+
+// endpoint clonar board (estrutura, ou estrutura e cards)
+func (app *App) cloneBoard(c *fiber.Ctx) error {
+	sourceID := authorizedBoardID(c)
+	userID := c.Locals("userID").(string)
+	var payload struct {
+		Title        string `json:"title"`
+		IncludeCards bool   `json:"include_cards"`
+	}
+	if err := c.BodyParser(&payload); err != nil {
+		return invalidBody(c)
+	}
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var source Board
+	if err := scanBoard(tx.QueryRow(ctx, "SELECT "+boardSelectColumns+" FROM boards WHERE id = $1", sourceID), &source); err != nil {
+		return internalError(c, "Erro ao buscar o quadro")
+	}
+	// a cópia é sempre um board privado do usuário
+	clone := Board{
+		Title:       payload.Title,
+		Description: source.Description,
+		Color:       source.Color,
+		OwnerID:     userID,
+	}
+	if clone.Title == "" {
+		clone.Title = source.Title + " (cópia)"
+	}
+	if err := validateBoard(&clone); err != nil {
+		return validationFailed(c, err)
+	}
+	err = scanBoard(tx.QueryRow(ctx, `INSERT INTO boards (title, description, owner_id, is_public, color)
+		VALUES ($1, $2, $3, false, $4) RETURNING `+boardSelectColumns,
+		clone.Title, clone.Description, userID, clone.Color), &clone)
+	if err != nil {
+		return internalError(c, "Erro ao criar o quadro")
+	}
+
+	if _, err := tx.Exec(ctx, `INSERT INTO labels (board_id, name, color)
+		SELECT $1, name, color FROM labels WHERE board_id = $2`, clone.ID, sourceID); err != nil {
+		return internalError(c, "Erro ao clonar etiquetas")
+	}
+
+	// pares coluna original -> coluna copiada
+	type columnPair struct{ from, to int }
+	columnPairs := make([]columnPair, 0)
+	rows, err := tx.Query(ctx, `SELECT id FROM columns WHERE board_id = $1 AND deleted_at IS NULL ORDER BY position`, sourceID)
+	if err != nil {
+		return internalError(c, "Erro ao ler colunas")
+	}
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return internalError(c, "Erro ao ler colunas")
+		}
+		columnPairs = append(columnPairs, columnPair{from: id})
+	}
+	rows.Close()
+
+	for i := range columnPairs {
+		err := tx.QueryRow(ctx, `INSERT INTO columns (board_id, title, position, color)
+			SELECT $1, title, position, color FROM columns WHERE id = $2 RETURNING id`,
+			clone.ID, columnPairs[i].from).Scan(&columnPairs[i].to)
+		if err != nil {
+			log.Printf("Erro ao clonar coluna %d: %v", columnPairs[i].from, err)
+			return internalError(c, "Erro ao clonar colunas")
+		}
+	}
+
+	if payload.IncludeCards {
+		for _, pair := range columnPairs {
+			if err := app.cloneColumnCards(ctx, tx, pair.from, pair.to, clone.ID, userID); err != nil {
+				log.Printf("Erro ao clonar cards da coluna %d: %v", pair.from, err)
+				return internalError(c, "Erro ao clonar cards")
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar clonagem")
+	}
+	return c.Status(201).JSON(clone)
+}
+
+// copiar os cards de uma coluna para outra, mantendo o arquivamento, os responsáveis
+// com acesso ao board destino e as etiquetas (já copiadas para o board destino, casadas pelo nome), registrando a criação no histórico
+func (app *App) cloneColumnCards(ctx context.Context, tx pgx.Tx, fromColumnID, toColumnID, boardID int, userID string) error {
+	rows, err := tx.Query(ctx, `SELECT id FROM cards WHERE column_id = $1 AND deleted_at IS NULL ORDER BY position`, fromColumnID)
+	if err != nil {
+		return err
+	}
+	var sourceIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		sourceIDs = append(sourceIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, sourceID := range sourceIDs {
+		var cardID int
+		var title string
+		err := tx.QueryRow(ctx, `
+			INSERT INTO cards (column_id, title, description, assigned_to, priority, due_date, position, completed_at, archived_at)
+			SELECT $1, title, description, '', priority, due_date, position, completed_at, archived_at
+			FROM cards WHERE id = $2
+			RETURNING id, title`, toColumnID, sourceID).Scan(&cardID, &title)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO card_labels (card_id, label_id)
+			SELECT $1, nl.id FROM card_labels cl
+			JOIN labels ol ON ol.id = cl.label_id
+			JOIN labels nl ON nl.board_id = $3 AND lower(nl.name) = lower(ol.name)
+			WHERE cl.card_id = $2`, cardID, sourceID, boardID); err != nil {
+			return err
+		}
+		if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventCreated, "", nil, fiber.Map{"title": title, "column_id": toColumnID}); err != nil {
+			return err
+		}
+		// só quem tem acesso à cópia continua responsável (em geral, só quem clonou)
+		source, err := app.loadCardAssignees(ctx, tx, []int{sourceID})
+		if err != nil {
+			return err
+		}
+		keep, err := assigneesWithAccess(ctx, tx, boardID, source[sourceID])
+		if err != nil {
+			return err
+		}
+		_, added, changed, err := app.updateCardAssignees(ctx, tx, cardID, boardID, userID, keep)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if _, err := tx.Exec(ctx, "UPDATE cards SET assigned_to = "+assignedToFromAssignees+" WHERE id = $1", cardID); err != nil {
+				return err
+			}
+			app.notifyNewAssignees(ctx, tx, added, boardID, cardID, title)
+		}
+	}
+	return nil
+}
+
+// template inicial "Padrão", igual às colunas que o createBoard sempre criou
+func (app *App) migrateDefaultBoardTemplate(ctx context.Context) error {
+	t := defaultBoardTemplate
+	_, err := app.db.Exec(ctx, `INSERT INTO board_templates (name, description, is_shared, columns, labels)
+		VALUES ($1, $2, $3, $4, $5)`, t.Name, t.Description, t.IsShared, t.Columns, t.Labels)
+	return err
+}
+
+// resolver o template pedido na criação do board; nil = padrão
+func (app *App) templateForNewBoard(ctx context.Context, tx pgx.Tx, templateID *int, userID string) (*BoardTemplate, error) {
+	if templateID == nil {
+		t := defaultBoardTemplate
+		return &t, nil
+	}
+	t, err := app.fetchVisibleTemplate(ctx, tx, *templateID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &ValidationError{Fields: []FieldError{{Field: "template_id", Code: "not_found", Message: "template não encontrado"}}}
+		}
+		return nil, err
+	}
+	return &t, nil
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// itens na lixeira ficam 30 dias por padrão (TRASH_RETENTION_DAYS)
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = time.Hour
+)
+
+// item excluído (board, coluna ou card)
+type TrashItem struct {
+	Kind       string    `json:"kind"`
+	ID         int       `json:"id"`
+	Title      string    `json:"title"`
+	BoardID    int       `json:"board_id"`
+	BoardTitle string    `json:"board_title"`
+	ColumnID   *int      `json:"column_id,omitempty"`
+	DeletedAt  time.Time `json:"deleted_at"`
+	DeletedBy  *string   `json:"deleted_by,omitempty"`
+	PurgeAt    time.Time `json:"purge_at"`
+}
+
+func trashRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("TRASH_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		days = defaultTrashRetentionDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// endpoint lixeira do usuário: boards dele e colunas/cards que ele excluiu ou de boards dele
+func (app *App) getTrash(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	rows, err := app.db.Query(context.Background(), `
+		SELECT 'board', b.id, b.title, b.id, b.title, NULL::int, b.deleted_at, b.deleted_by::text
+		FROM boards b
+		WHERE b.deleted_at IS NOT NULL AND b.owner_id = $1
+		UNION ALL
+		SELECT 'column', col.id, col.title, b.id, b.title, NULL::int, col.deleted_at, col.deleted_by::text
+		FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.deleted_at IS NOT NULL AND b.deleted_at IS NULL AND (col.deleted_by = $1 OR b.owner_id = $1)
+		UNION ALL
+		SELECT 'card', ca.id, ca.title, b.id, b.title, ca.column_id, ca.deleted_at, ca.deleted_by::text
+		FROM cards ca JOIN columns col ON col.id = ca.column_id JOIN boards b ON b.id = col.board_id
+		WHERE ca.deleted_at IS NOT NULL AND b.deleted_at IS NULL AND (ca.deleted_by = $1 OR b.owner_id = $1)
+		ORDER BY 7 DESC`, userID)
+	if err != nil {
+		log.Printf("Erro ao buscar lixeira de %s: %v", userID, err)
+		return internalError(c, "Erro ao buscar a lixeira")
+	}
+	defer rows.Close()
+
+	retention := trashRetention()
+	items := make([]TrashItem, 0)
+	for rows.Next() {
+		var item TrashItem
+		if err := rows.Scan(&item.Kind, &item.ID, &item.Title, &item.BoardID, &item.BoardTitle, &item.ColumnID,
+			&item.DeletedAt, &item.DeletedBy); err != nil {
+			return internalError(c, "Erro ao ler item da lixeira")
+		}
+		item.PurgeAt = item.DeletedAt.Add(retention)
+		items = append(items, item)
+	}
+	return c.JSON(items)
+}
+
+func notInTrash(c *fiber.Ctx) error {
+	return notFound(c, "Item não encontrado na lixeira")
+}
+
+// endpoint restaurar board (apenas o dono)
+func (app *App) restoreBoard(c *fiber.Ctx) error {
+	boardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do quadro inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var board Board
+	err = scanBoard(tx.QueryRow(ctx, `UPDATE boards SET deleted_at = NULL, deleted_by = NULL, updated_at = NOW(), version = version + 1
+		WHERE id = $1 AND owner_id = $2 AND deleted_at IS NOT NULL
+		RETURNING `+boardSelectColumns, boardID, userID), &board)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notInTrash(c)
+		}
+		return internalError(c, "Erro ao restaurar o quadro")
+	}
+	// quadro público apagado antes das colunas de status volta com elas
+	if _, err := applyBoardVisibility(ctx, tx, &board); err != nil {
+		log.Printf("Erro ao criar colunas de status do board %d: %v", boardID, err)
+		return internalError(c, "Erro ao restaurar o quadro")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar restauração")
+	}
+	return c.JSON(board)
+}
+
+// endpoint restaurar coluna; volta no fim do board
+func (app *App) restoreColumn(c *fiber.Ctx) error {
+	columnID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID da coluna inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var boardID int
+	var title string
+	var isPublic bool
+	var deletedAt time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT col.board_id, col.title, b.is_public, col.deleted_at FROM columns col JOIN boards b ON b.id = col.board_id
+		WHERE col.id = $1 AND col.deleted_at IS NOT NULL AND b.deleted_at IS NULL
+		FOR UPDATE OF col`, columnID).Scan(&boardID, &title, &isPublic, &deletedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notInTrash(c)
+		}
+		return internalError(c, "Erro ao buscar a coluna")
+	}
+	if !app.requireBoardRole(c, boardID, RoleEditor) {
+		return nil
+	}
+	// o board pode ter virado público e ganho as colunas de status nesse meio tempo
+	if isPublic && isSystemColumnTitle(title) {
+		return validationFailed(c, reservedColumnTitle())
+	}
+
+	var maxPos sql.NullInt64
+	if err := tx.QueryRow(ctx, "SELECT MAX(position) FROM columns WHERE board_id = $1 AND deleted_at IS NULL", boardID).Scan(&maxPos); err != nil {
+		return internalError(c, "Erro ao calcular a posição da coluna")
+	}
+	position := 0
+	if maxPos.Valid {
+		position = int(maxPos.Int64) + 1
+	}
+
+	var col Column
+	err = scanColumn(tx.QueryRow(ctx, `UPDATE columns SET deleted_at = NULL, deleted_by = NULL, position = $2, version = version + 1
+		WHERE id = $1 RETURNING `+columnSelectColumns, columnID, position), &col)
+	if err != nil {
+		return internalError(c, "Erro ao restaurar a coluna")
+	}
+	// os arquivados que foram para a lixeira junto com a coluna
+	rows, err := tx.Query(ctx, `UPDATE cards SET deleted_at = NULL, deleted_by = NULL
+		WHERE column_id = $1 AND deleted_at = $2 AND archived_at IS NOT NULL
+		RETURNING id, title`, columnID, deletedAt)
+	if err != nil {
+		return internalError(c, "Erro ao restaurar os cards arquivados")
+	}
+	archived := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var cardTitle string
+		if err := rows.Scan(&id, &cardTitle); err != nil {
+			rows.Close()
+			return internalError(c, "Erro ao restaurar os cards arquivados")
+		}
+		archived[id] = cardTitle
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return internalError(c, "Erro ao restaurar os cards arquivados")
+	}
+	for id, cardTitle := range archived {
+		if err := app.recordCardEvent(tx, id, boardID, userID, CardEventRestored, "", nil, fiber.Map{"title": cardTitle, "column_id": columnID}); err != nil {
+			log.Printf("Erro ao registrar histórico do card %d: %v", id, err)
+			return internalError(c, "Erro ao registrar histórico")
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar restauração")
+	}
+
+	app.broadcast(boardID, WsMessage{Type: "BOARD_STATE_UPDATED", SenderID: userID, Payload: nil})
+	return c.JSON(col)
+}
+
+// endpoint restaurar card; volta no fim da coluna original, ou da primeira coluna se ela não existir mais
+func (app *App) restoreCard(c *fiber.Ctx) error {
+	cardID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID do card inválido")
+	}
+	userID := c.Locals("userID").(string)
+
+	ctx := context.Background()
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return internalError(c, "Erro ao iniciar transação")
+	}
+	defer tx.Rollback(ctx)
+
+	var boardID, columnID int
+	var columnDeleted bool
+	err = tx.QueryRow(ctx, `
+		SELECT col.board_id, ca.column_id, col.deleted_at IS NOT NULL
+		FROM cards ca JOIN columns col ON col.id = ca.column_id JOIN boards b ON b.id = col.board_id
+		WHERE ca.id = $1 AND ca.deleted_at IS NOT NULL AND b.deleted_at IS NULL
+		FOR UPDATE OF ca`, cardID).Scan(&boardID, &columnID, &columnDeleted)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notInTrash(c)
+		}
+		return internalError(c, "Erro ao buscar o card")
+	}
+	if !app.requireBoardRole(c, boardID, RoleEditor) {
+		return nil
+	}
+
+	if columnDeleted {
+		err = tx.QueryRow(ctx, `SELECT id FROM columns WHERE board_id = $1 AND deleted_at IS NULL
+			ORDER BY position LIMIT 1`, boardID).Scan(&columnID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "column_id", Code: "no_column", Message: "o quadro não tem colunas para receber o card"}}})
+		}
+		if err != nil {
+			return internalError(c, "Erro ao buscar coluna de destino")
+		}
+	}
+
+	var maxPos sql.NullInt64
+	if err := tx.QueryRow(ctx, "SELECT MAX(position) FROM cards WHERE column_id = $1 AND archived_at IS NULL AND deleted_at IS NULL", columnID).Scan(&maxPos); err != nil {
+		return internalError(c, "Erro ao calcular a posição do card")
+	}
+
+	var card Card
+	err = scanCard(tx.QueryRow(ctx, `UPDATE cards SET deleted_at = NULL, deleted_by = NULL, column_id = $2, position = $3,
+		updated_at = NOW(), version = version + 1
+		WHERE id = $1 RETURNING `+cardSelectColumns, cardID, columnID, int(maxPos.Int64)+1), &card)
+	if err != nil {
+		return internalError(c, "Erro ao restaurar o card")
+	}
+	if err := app.recordCardEvent(tx, cardID, boardID, userID, CardEventRestored, "", nil, fiber.Map{"title": card.Title, "column_id": columnID}); err != nil {
+		log.Printf("Erro ao registrar histórico do card %d: %v", cardID, err)
+		return internalError(c, "Erro ao registrar histórico")
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return internalError(c, "Erro ao confirmar restauração")
+	}
+
+	// card que já estava arquivado volta para o arquivo, sem aparecer no quadro
+	if card.ArchivedAt == nil {
+		app.broadcast(boardID, WsMessage{Type: "CARD_CREATED", SenderID: userID, Payload: card})
+	}
+	return c.JSON(card)
+}
+
+// remover de vez o que passou do período de retenção
+func (app *App) purgeTrash(ctx context.Context) error {
+	cutoff := time.Now().Add(-trashRetention())
+	tx, err := app.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// cards vencidos e tudo que ainda aponta para uma coluna vencida, para ela poder sair
+	cards, err := tx.Exec(ctx, `DELETE FROM cards WHERE deleted_at < $1
+		OR column_id IN (SELECT id FROM columns WHERE deleted_at < $1)`, cutoff)
+	if err != nil {
+		return err
+	}
+	columns, err := tx.Exec(ctx, "DELETE FROM columns WHERE deleted_at < $1", cutoff)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.Query(ctx, "DELETE FROM boards WHERE deleted_at < $1 RETURNING id", cutoff)
+	if err != nil {
+		return err
+	}
+	var boardIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		boardIDs = append(boardIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(boardIDs) > 0 {
+		if _, err := tx.Exec(ctx, "DELETE FROM board_event_log WHERE board_id = ANY($1)", boardIDs); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM board_sequences WHERE board_id = ANY($1)", boardIDs); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if n := cards.RowsAffected() + columns.RowsAffected() + int64(len(boardIDs)); n > 0 {
+		log.Printf("Lixeira: %d cards, %d colunas e %d quadros removidos definitivamente",
+			cards.RowsAffected(), columns.RowsAffected(), len(boardIDs))
+	}
+	return nil
+}
+
+func (app *App) runTrashPurge(ctx context.Context) {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+	for {
+		if err := app.purgeTrash(ctx); err != nil {
+			log.Printf("Erro ao limpar a lixeira: %v", err)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
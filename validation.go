@@ -0,0 +1,240 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// enums espelhando os tipos do frontend
+var (
+	cardPriorities  = []string{"baixa", "media", "alta"}
+	reviewStatuses  = []string{"Pendente", "Em Tratamento", "Resolvido", "Ignorado"}
+	reviewSources   = []string{"Google", "ReclameAqui", "Procon", "ANATEL", "Outros"}
+	contatoStatuses = []string{"pendente", "Agendado O.S.", "Nao conseguido contato", "Nao solucionado"}
+	ligacaoTypes    = []string{"Condomínio", "Bairro", "Outros"}
+)
+
+// limites de tamanho dos campos de texto
+const (
+	maxTitleLength       = 255
+	maxColumnTitleLength = 100
+	maxShortTextLength   = 255
+	maxURLLength         = 2048
+	maxLongTextLength    = 10000
+)
+
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// códigos de erro da API
+const (
+	ErrCodeValidation   = "validation_failed"
+	ErrCodeInvalidBody  = "invalid_body"
+	ErrCodeBadRequest   = "bad_request"
+	ErrCodeUnauthorized = "unauthorized"
+	ErrCodeConflict     = "version_conflict"
+	ErrCodeInternal     = "internal_error"
+)
+
+// erro de um campo específico
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// corpo padrão de erro: {error: {code, message, fields}}
+type APIError struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// erro com a lista de campos inválidos
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		parts = append(parts, f.Field+": "+f.Message)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// acumula erros de campo
+type Validator struct {
+	fields []FieldError
+}
+
+func (v *Validator) Add(field, code, message string) {
+	v.fields = append(v.fields, FieldError{Field: field, Code: code, Message: message})
+}
+
+// texto obrigatório (não vazio após trim) com limite de tamanho
+func (v *Validator) Required(field, value string, max int) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "required", "campo obrigatório")
+		return
+	}
+	v.MaxLength(field, value, max)
+}
+
+func (v *Validator) MaxLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, "too_long", fmt.Sprintf("máximo de %d caracteres", max))
+	}
+}
+
+func (v *Validator) OptionalMaxLength(field string, value *string, max int) {
+	if value != nil {
+		v.MaxLength(field, *value, max)
+	}
+}
+
+func (v *Validator) OneOf(field, value string, allowed []string) {
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.Add(field, "invalid_choice", "valor inválido, use: "+strings.Join(allowed, ", "))
+}
+
+// cor no formato #rgb ou #rrggbb; vazio é aceito quando opcional
+func (v *Validator) HexColor(field, value string, required bool) {
+	if value == "" && !required {
+		return
+	}
+	if !hexColorPattern.MatchString(value) {
+		v.Add(field, "invalid_color", "cor deve estar no formato hexadecimal (#rrggbb)")
+	}
+}
+
+func (v *Validator) NonNegative(field string, n int) {
+	if n < 0 {
+		v.Add(field, "negative", "não pode ser negativo")
+	}
+}
+
+func (v *Validator) Range(field string, n, min, max int) {
+	if n < min || n > max {
+		v.Add(field, "out_of_range", fmt.Sprintf("deve estar entre %d e %d", min, max))
+	}
+}
+
+func (v *Validator) Err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// resposta de erro no formato padrão
+func errorResponse(c *fiber.Ctx, status int, code, message string, fields []FieldError) error {
+	return c.Status(status).JSON(fiber.Map{"error": APIError{Code: code, Message: message, Fields: fields}})
+}
+
+// 400 com os campos inválidos
+func validationFailed(c *fiber.Ctx, err error) error {
+	if verr, ok := err.(*ValidationError); ok {
+		return errorResponse(c, fiber.StatusBadRequest, ErrCodeValidation, "Dados inválidos", verr.Fields)
+	}
+	return errorResponse(c, fiber.StatusBadRequest, ErrCodeValidation, err.Error(), nil)
+}
+
+// 400 para corpo que não pôde ser lido
+func invalidBody(c *fiber.Ctx) error {
+	return errorResponse(c, fiber.StatusBadRequest, ErrCodeInvalidBody, "Corpo da requisição inválido", nil)
+}
+
+// 400 genérico, fora da validação de campos
+func badRequest(c *fiber.Ctx, message string) error {
+	return errorResponse(c, fiber.StatusBadRequest, ErrCodeBadRequest, message, nil)
+}
+
+func unauthorized(c *fiber.Ctx, message string) error {
+	return errorResponse(c, fiber.StatusUnauthorized, ErrCodeUnauthorized, message, nil)
+}
+
+// 500 sem detalhes internos; o erro real fica no log
+func internalError(c *fiber.Ctx, message string) error {
+	return errorResponse(c, fiber.StatusInternalServerError, ErrCodeInternal, message, nil)
+}
+
+// falha de autenticação: 401 ou 500 quando o servidor está mal configurado
+func authFailed(c *fiber.Ctx, status int, message string) error {
+	if status == fiber.StatusUnauthorized {
+		return unauthorized(c, message)
+	}
+	return internalError(c, message)
+}
+
+func validateCard(card *Card) error {
+	var v Validator
+	v.Required("title", card.Title, maxTitleLength)
+	v.MaxLength("description", card.Description, maxLongTextLength)
+	v.MaxLength("assigned_to", card.AssignedTo, maxShortTextLength)
+	v.OneOf("priority", card.Priority, cardPriorities)
+	return v.Err()
+}
+
+func validateColumn(col *Column) error {
+	var v Validator
+	v.Required("title", col.Title, maxColumnTitleLength)
+	v.HexColor("color", col.Color, false)
+	return v.Err()
+}
+
+func validateBoard(board *Board) error {
+	var v Validator
+	v.Required("title", board.Title, maxColumnTitleLength)
+	v.MaxLength("description", board.Description, maxLongTextLength)
+	v.HexColor("color", board.Color, false)
+	v.OptionalMaxLength("team", board.Team, maxShortTextLength)
+	return v.Err()
+}
+
+func validateLigacao(l *Ligacao) error {
+	var v Validator
+	v.Required("name", l.Name, maxShortTextLength)
+	v.OneOf("type", l.Type, ligacaoTypes)
+	v.Required("status", l.Status, maxShortTextLength)
+	v.OptionalMaxLength("spreadsheet_url", l.SpreadsheetURL, maxURLLength)
+	v.OptionalMaxLength("address", l.Address, maxShortTextLength)
+	v.OptionalMaxLength("observations", l.Observations, maxLongTextLength)
+	return v.Err()
+}
+
+func validateAgendaEvent(e *AgendaEvent) error {
+	var v Validator
+	v.Required("title", e.Title, maxTitleLength)
+	v.OptionalMaxLength("description", e.Description, maxLongTextLength)
+	v.HexColor("color", e.Color, true)
+	if e.EventDate.IsZero() {
+		v.Add("event_date", "required", "campo obrigatório")
+	}
+	return v.Err()
+}
+
+func validateAvaliacao(a *Avaliacao) error {
+	var v Validator
+	v.OneOf("source", a.Source, reviewSources)
+	v.Required("customer_name", a.CustomerName, maxShortTextLength)
+	v.Required("review_content", a.ReviewContent, maxLongTextLength)
+	if a.Rating != nil {
+		v.Range("rating", *a.Rating, 1, 5)
+	}
+	v.OneOf("status", a.Status, reviewStatuses)
+	if a.ReviewDate.IsZero() {
+		v.Add("review_date", "required", "campo obrigatório")
+	}
+	v.OptionalMaxLength("review_url", a.ReviewURL, maxURLLength)
+	v.OptionalMaxLength("assigned_to", a.AssignedTo, maxShortTextLength)
+	v.OptionalMaxLength("resolution_notes", a.ResolutionNotes, maxLongTextLength)
+	return v.Err()
+}
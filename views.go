@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/jackc/pgx/v5"
+)
+
+// limite de cards carregados ao montar o board virtual de uma visão
+const maxViewBoardCards = 500
+
+// agrupamentos do board virtual
+var viewGroupings = []string{"column", "assignee", "priority"}
+
+// filtros salvos: os da busca mais os relativos ao usuário e à data de execução
+type ViewFilters struct {
+	CardSearchFilter
+	AssignedToMe  bool `json:"assigned_to_me,omitempty"`
+	DueWithinDays *int `json:"due_within_days,omitempty"`
+}
+
+// estrutura visão salva; compartilhada = visível aos membros do board
+type SavedView struct {
+	ID        int         `json:"id" db:"id"`
+	Name      string      `json:"name" db:"name"`
+	OwnerID   string      `json:"owner_id" db:"owner_id"`
+	BoardID   *int        `json:"board_id,omitempty" db:"board_id"`
+	IsShared  bool        `json:"is_shared" db:"is_shared"`
+	Filters   ViewFilters `json:"filters" db:"filters"`
+	GroupBy   string      `json:"group_by,omitempty" db:"group_by"`
+	CreatedAt time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at" db:"updated_at"`
+}
+
+const viewSelectColumns = `id, name, owner_id::text, board_id, is_shared, filters, group_by, created_at, updated_at`
+
+func scanView(row pgx.Row, v *SavedView) error {
+	return row.Scan(&v.ID, &v.Name, &v.OwnerID, &v.BoardID, &v.IsShared, &v.Filters, &v.GroupBy, &v.CreatedAt, &v.UpdatedAt)
+}
+
+// grupo do board virtual
+type ViewGroup struct {
+	Key   string             `json:"key"`
+	Title string             `json:"title"`
+	Cards []CardSearchResult `json:"cards"`
+}
+
+func validateView(view *SavedView) error {
+	var v Validator
+	v.Required("name", view.Name, maxTitleLength)
+	if view.GroupBy != "" {
+		v.OneOf("group_by", view.GroupBy, viewGroupings)
+	}
+	if view.IsShared && view.BoardID == nil {
+		v.Add("board_id", "required", "informe o board com o qual a visão será compartilhada")
+	}
+	validateSearchFilter(&v, "filters.", view.Filters.CardSearchFilter)
+	if view.Filters.DueWithinDays != nil {
+		v.NonNegative("filters.due_within_days", *view.Filters.DueWithinDays)
+	}
+	return v.Err()
+}
+
+// visão acessível: do usuário, ou compartilhada em um board que ele acessa
+func (app *App) fetchVisibleView(ctx context.Context, viewID int, userID string) (SavedView, error) {
+	var view SavedView
+	err := scanView(app.db.QueryRow(ctx, `SELECT `+viewSelectColumns+` FROM saved_views sv
+		WHERE sv.id = $1 AND (sv.owner_id::text = $2 OR (sv.is_shared AND EXISTS (
+			SELECT 1 FROM boards b WHERE b.id = sv.board_id AND b.deleted_at IS NULL AND `+boardAccessCondition("b", "$2")+`)))`,
+		viewID, userID), &view)
+	return view, err
+}
+
+// resolver os filtros relativos no momento da execução
+func (app *App) resolveViewFilters(ctx context.Context, f ViewFilters, userID string) (CardSearchFilter, error) {
+	resolved := f.CardSearchFilter
+	if f.AssignedToMe {
+		resolved.AssigneeID = userID
+	}
+	if f.DueWithinDays != nil {
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		until := today.AddDate(0, 0, *f.DueWithinDays+1).Add(-time.Nanosecond)
+		resolved.DueFrom = &today
+		resolved.DueTo = &until
+	}
+	return resolved, nil
+}
+
+// sem acesso ao board não dá para compartilhar a visão nele
+func (app *App) checkViewBoard(c *fiber.Ctx, view *SavedView, userID string) bool {
+	if view.BoardID == nil {
+		return true
+	}
+	role, err := app.boardRole(userID, *view.BoardID)
+	if err != nil {
+		internalError(c, "Erro ao verificar permissão")
+		return false
+	}
+	if role == "" {
+		validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "board_id", Code: "not_found", Message: "board não encontrado"}}})
+		return false
+	}
+	return true
+}
+
+// endpoint listar visões (próprias e compartilhadas); ?board_id= filtra por board
+func (app *App) getViews(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	query := `SELECT ` + viewSelectColumns + ` FROM saved_views sv
+		WHERE (sv.owner_id::text = $1 OR (sv.is_shared AND EXISTS (
+			SELECT 1 FROM boards b WHERE b.id = sv.board_id AND b.deleted_at IS NULL AND ` + boardAccessCondition("b", "$1") + `)))`
+	args := []interface{}{userID}
+	if raw := c.Query("board_id"); raw != "" {
+		boardID, err := strconv.Atoi(raw)
+		if err != nil {
+			return badRequest(c, "ID do quadro inválido")
+		}
+		query += ` AND sv.board_id = $2`
+		args = append(args, boardID)
+	}
+	query += ` ORDER BY sv.owner_id::text <> $1, lower(sv.name)`
+
+	rows, err := app.db.Query(context.Background(), query, args...)
+	if err != nil {
+		log.Printf("Erro ao buscar visões: %v", err)
+		return internalError(c, "Erro ao buscar visões")
+	}
+	defer rows.Close()
+	views := make([]SavedView, 0)
+	for rows.Next() {
+		var view SavedView
+		if err := scanView(rows, &view); err != nil {
+			return internalError(c, "Erro ao ler visão")
+		}
+		views = append(views, view)
+	}
+	return c.JSON(views)
+}
+
+// endpoint buscar visão
+func (app *App) getView(c *fiber.Ctx) error {
+	viewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID da visão inválido")
+	}
+	view, err := app.fetchVisibleView(context.Background(), viewID, c.Locals("userID").(string))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Visão não encontrada")
+		}
+		return internalError(c, "Erro ao buscar visão")
+	}
+	return c.JSON(view)
+}
+
+// endpoint criar visão
+func (app *App) createView(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	var view SavedView
+	if err := c.BodyParser(&view); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateView(&view); err != nil {
+		return validationFailed(c, err)
+	}
+	if !app.checkViewBoard(c, &view, userID) {
+		return nil
+	}
+
+	err := scanView(app.db.QueryRow(context.Background(), `
+		INSERT INTO saved_views (name, owner_id, board_id, is_shared, filters, group_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+viewSelectColumns, view.Name, userID, view.BoardID, view.IsShared, view.Filters, view.GroupBy), &view)
+	if err != nil {
+		log.Printf("Erro ao criar visão: %v", err)
+		return internalError(c, "Erro ao criar visão")
+	}
+	return c.Status(201).JSON(view)
+}
+
+// endpoint atualizar visão (apenas o autor)
+func (app *App) updateView(c *fiber.Ctx) error {
+	viewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID da visão inválido")
+	}
+	userID := c.Locals("userID").(string)
+	var view SavedView
+	if err := c.BodyParser(&view); err != nil {
+		return invalidBody(c)
+	}
+	if err := validateView(&view); err != nil {
+		return validationFailed(c, err)
+	}
+	if !app.checkViewBoard(c, &view, userID) {
+		return nil
+	}
+
+	err = scanView(app.db.QueryRow(context.Background(), `
+		UPDATE saved_views SET name = $3, board_id = $4, is_shared = $5, filters = $6, group_by = $7, updated_at = NOW()
+		WHERE id = $1 AND owner_id::text = $2
+		RETURNING `+viewSelectColumns, viewID, userID, view.Name, view.BoardID, view.IsShared, view.Filters, view.GroupBy), &view)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound(c, "Visão não encontrada")
+		}
+		log.Printf("Erro ao atualizar visão %d: %v", viewID, err)
+		return internalError(c, "Erro ao atualizar visão")
+	}
+	return c.JSON(view)
+}
+
+// endpoint excluir visão (apenas o autor)
+func (app *App) deleteView(c *fiber.Ctx) error {
+	viewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return badRequest(c, "ID da visão inválido")
+	}
+	tag, err := app.db.Exec(context.Background(),
+		"DELETE FROM saved_views WHERE id = $1 AND owner_id::text = $2", viewID, c.Locals("userID").(string))
+	if err != nil {
+		return internalError(c, "Erro ao excluir visão")
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound(c, "Visão não encontrada")
+	}
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// carregar a visão e resolver seus filtros para o usuário que executa
+func (app *App) loadViewForExecution(c *fiber.Ctx) (SavedView, CardSearchFilter, bool) {
+	ctx := context.Background()
+	userID := c.Locals("userID").(string)
+	viewID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		badRequest(c, "ID da visão inválido")
+		return SavedView{}, CardSearchFilter{}, false
+	}
+	view, err := app.fetchVisibleView(ctx, viewID, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			notFound(c, "Visão não encontrada")
+		} else {
+			internalError(c, "Erro ao buscar visão")
+		}
+		return view, CardSearchFilter{}, false
+	}
+	filter, err := app.resolveViewFilters(ctx, view.Filters, userID)
+	if err != nil {
+		log.Printf("Erro ao resolver filtros da visão %d: %v", viewID, err)
+		internalError(c, "Erro ao resolver filtros da visão")
+		return view, filter, false
+	}
+	return view, filter, true
+}
+
+// endpoint executar visão (paginado como a busca)
+func (app *App) getViewCards(c *fiber.Ctx) error {
+	view, filter, ok := app.loadViewForExecution(c)
+	if !ok {
+		return nil
+	}
+	limit, _ := strconv.Atoi(c.Query("limit", strconv.Itoa(defaultSearchLimit)))
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = defaultSearchLimit
+	}
+	var cursor *searchCursor
+	if raw := c.Query("cursor"); raw != "" {
+		cur, err := decodeSearchCursor(raw)
+		if err != nil {
+			return validationFailed(c, &ValidationError{Fields: []FieldError{{Field: "cursor", Code: "invalid_cursor", Message: "cursor inválido"}}})
+		}
+		cursor = &cur
+	}
+
+	results, next, err := app.searchCards(context.Background(), c.Locals("userID").(string), filter, cursor, limit)
+	if err != nil {
+		log.Printf("Erro ao executar visão %d: %v", view.ID, err)
+		return internalError(c, "Erro ao executar visão")
+	}
+	var nextCursor interface{}
+	if next != "" {
+		nextCursor = next
+	}
+	return c.JSON(fiber.Map{"view": view, "cards": results, "next_cursor": nextCursor})
+}
+
+// endpoint board virtual: resultados da visão agrupados por coluna, responsável ou prioridade
+func (app *App) getViewBoard(c *fiber.Ctx) error {
+	view, filter, ok := app.loadViewForExecution(c)
+	if !ok {
+		return nil
+	}
+	groupBy := c.Query("group_by", view.GroupBy)
+	if groupBy == "" {
+		groupBy = "column"
+	}
+	var v Validator
+	v.OneOf("group_by", groupBy, viewGroupings)
+	if err := v.Err(); err != nil {
+		return validationFailed(c, err)
+	}
+
+	results, next, err := app.searchCards(context.Background(), c.Locals("userID").(string), filter, nil, maxViewBoardCards)
+	if err != nil {
+		log.Printf("Erro ao executar visão %d: %v", view.ID, err)
+		return internalError(c, "Erro ao executar visão")
+	}
+	return c.JSON(fiber.Map{
+		"view":      view,
+		"group_by":  groupBy,
+		"groups":    groupViewCards(results, groupBy),
+		"truncated": next != "",
+	})
+}
+
+// agrupar mantendo a ordem da busca dentro de cada grupo; por responsável,
+// um card com vários responsáveis aparece em cada um dos grupos
+func groupViewCards(cards []CardSearchResult, groupBy string) []ViewGroup {
+	index := make(map[string]int)
+	groups := make([]ViewGroup, 0)
+	add := func(key, title string, card CardSearchResult) {
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, ViewGroup{Key: key, Title: title, Cards: make([]CardSearchResult, 0)})
+		}
+		groups[i].Cards = append(groups[i].Cards, card)
+	}
+	for _, card := range cards {
+		switch groupBy {
+		case "column":
+			add(strconv.Itoa(card.ColumnID), card.BoardTitle+" / "+card.ColumnTitle, card)
+		case "assignee":
+			if len(card.Assignees) == 0 {
+				add("", "Sem responsável", card)
+			}
+			for _, user := range card.Assignees {
+				add(user.ID, user.Name, card)
+			}
+		case "priority":
+			add(card.Priority, card.Priority, card)
+		}
+	}
+
+	priorityOrder := map[string]int{"alta": 0, "media": 1, "baixa": 2}
+	sort.SliceStable(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		switch groupBy {
+		case "priority":
+			return priorityOrder[a.Key] < priorityOrder[b.Key]
+		case "assignee":
+			// sem responsável por último
+			if (a.Key == "") != (b.Key == "") {
+				return b.Key == ""
+			}
+		}
+		return a.Title < b.Title
+	})
+	return groups
+}